@@ -0,0 +1,504 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// AOFSyncPolicy policy of fsync of append-only file
+type AOFSyncPolicy int
+
+const (
+	// AOFSyncEverySecond fsync every second (default)
+	AOFSyncEverySecond AOFSyncPolicy = iota
+	// AOFSyncAlways fsync on every write
+	AOFSyncAlways
+	// AOFSyncNever leave fsync to OS
+	AOFSyncNever
+)
+
+const (
+	// DefaultAOFRewriteMinSize minimum size of append-only file to be rewritten
+	DefaultAOFRewriteMinSize int64 = 64 << 20
+)
+
+// operation of aofRecord
+const (
+	aofSet = iota
+	aofDel
+)
+
+// format of payload of aofRecord, which is the first byte of payload
+const (
+	aofPlain = iota
+	aofEncrypted
+)
+
+const (
+	aofHeaderSize = 8 // length and crc32 of record
+	aofMaxRecordSize = 1 << 30
+)
+
+// aofRecord record of append-only file.
+type aofRecord[K comparable] struct {
+	Op int
+	Item itemRecord[K]
+}
+
+// aofEntry change of item queued to append-only file.
+type aofEntry[K comparable, V any] struct {
+	item *TypedItem[K, V]
+	deleted bool
+}
+
+// aofLog append-only file.
+// Records are length-prefixed and checksummed, so that torn record at the end is detected on replay.
+// If KeyProvider is set in option, records are encrypted.
+// Changes are queued under the lock of shard, and encoded, written and synced by background goroutine,
+// so that shards are not blocked by encoding and fsync.
+type aofLog[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	path string
+	file *os.File // written only by background goroutine
+	w *bufio.Writer
+	size int64 // current size of file
+	base int64 // size of file after last rewrite
+
+	mu sync.Mutex // guards fields below
+	cond *sync.Cond // signaled when queued changes are written
+	queue []aofEntry[K, V]
+	queued uint64 // sequence of last queued change
+	written uint64 // sequence of last written change
+	failedFrom uint64 // sequences of changes of last failed write
+	failedTo uint64
+	failed error
+	closed bool
+
+	wake chan bool
+	rewrites chan chan error
+	stop chan bool
+	closeErr error // Error of last sync on close
+	wg sync.WaitGroup
+}
+
+// OpenAOF replay append-only file, and append Set, Del and eviction to it.
+// File is created if it does not exist. Torn record at the end of file is truncated.
+// File is rewritten in background when it grows over AOFRewriteMinSize and twice of the size after last rewrite.
+// Errors of writing are passed to OnAOFError in option, and returned from Set and Del in AOFSyncAlways.
+// param path - path of file
+// return arg1 - Error
+func (c *cache[K, V]) OpenAOF(path string) error {
+	if c.aof.Load() != nil {
+		return errors.New("append-only file is already opened")
+	}
+	file, err := os.OpenFile(path, os.O_RDWR | os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	size, err := c.replay(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return err
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return err
+	}
+
+	l := &aofLog[K, V]{
+		option: c.option,
+		path: path,
+		file: file,
+		w: bufio.NewWriter(file),
+		size: size,
+		base: size,
+		wake: make(chan bool, 1),
+		rewrites: make(chan chan error),
+		stop: make(chan bool),
+	}
+	l.cond = sync.NewCond(&l.mu)
+	c.aof.Store(l)
+	l.wg.Add(1)
+	go l.run(c)
+	return nil
+}
+
+// RewriteAOF rewrite append-only file by current items.
+// return arg1 - Error
+func (c *cache[K, V]) RewriteAOF() error {
+	l := c.aof.Load()
+	if l == nil {
+		return errors.New("append-only file is not opened")
+	}
+	done := make(chan error, 1)
+	select {
+	case l.rewrites <- done:
+		return <-done
+	case <-l.stop:
+		return errors.New("append-only file is closed")
+	}
+}
+
+// closeAOF stop appending, and sync append-only file.
+func (c *cache[K, V]) closeAOF() error {
+	l := c.aof.Swap(nil)
+	if l == nil {
+		return nil
+	}
+	close(l.stop)
+	l.wg.Wait()
+
+	err := l.closeErr
+	if cerr := l.file.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// journal queue item to append-only file.
+// This is called under the lock of shard, so that records are in order of change.
+// param item - item which is set or deleted
+// param deleted - If item is deleted, true.
+func (c *cache[K, V]) journal(item *TypedItem[K, V], deleted bool) {
+	l := c.aof.Load()
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return
+	}
+	l.queue = append(l.queue, aofEntry[K, V]{item: item, deleted: deleted})
+	l.queued++
+	l.mu.Unlock()
+
+	select {
+	case l.wake <- true:
+	default:
+	}
+}
+
+// syncAOF wait until changes queued so far are written and synced in AOFSyncAlways.
+// return arg1 - Error of writing changes
+func (c *cache[K, V]) syncAOF() error {
+	if c.option.AOFSync != AOFSyncAlways {
+		return nil
+	}
+	l := c.aof.Load()
+	if l == nil {
+		return nil
+	}
+	return l.wait()
+}
+
+// replay apply records of append-only file to cache.
+// param r - append-only file
+// return arg1 - size of valid records
+// return arg2 - Error
+func (c *cache[K, V]) replay(r io.Reader) (int64, error) {
+	br := bufio.NewReader(r)
+	var size int64
+	count := 0
+	for {
+		payload, n, err := readAOFRecord(br)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			Warn("append-only file is truncated. offset = %d error = %v", size, err)
+			break
+		}
+		var record aofRecord[K]
+		if err := decodeAOFRecord(payload, c.option.KeyProvider, &record); err != nil {
+			return size, err
+		}
+		switch record.Op {
+		case aofSet:
+			if _, err := c.restore(record.Item); err != nil {
+				return size, err
+			}
+		case aofDel:
+			c.shard(record.Item.Key).remove(record.Item.Key)
+		}
+		size += n
+		count++
+	}
+	Debug("replayed append-only file. records = %d size = %d", count, size)
+	return size, nil
+}
+
+// wait wait until changes queued so far are written.
+// return arg1 - Error of write including the changes
+func (l *aofLog[K, V]) wait() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seq := l.queued
+	for l.written < seq && !l.closed {
+		l.cond.Wait()
+	}
+	if l.failed != nil && l.failedFrom <= seq && seq <= l.failedTo {
+		return l.failed
+	}
+	if l.written < seq {
+		return errors.New("append-only file is closed")
+	}
+	return nil
+}
+
+// run write queued changes, sync file every second, and rewrite it when it grows.
+func (l *aofLog[K, V]) run(c *cache[K, V]) {
+	defer l.wg.Done()
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.wake:
+			l.flush(c)
+		case <-ticker.C:
+			l.flush(c)
+			if err := l.sync(); err != nil {
+				l.fail(fmt.Errorf("append-only file sync: %w", err))
+			}
+			if l.needRewrite() {
+				if err := l.rewrite(c); err != nil {
+					l.fail(fmt.Errorf("append-only file rewrite: %w", err))
+				}
+			}
+		case done := <-l.rewrites:
+			l.flush(c)
+			done <- l.rewrite(c)
+		case <-l.stop:
+			l.mu.Lock()
+			l.closed = true
+			l.mu.Unlock()
+			l.flush(c)
+			l.closeErr = l.sync()
+			l.cond.Broadcast()
+			return
+		}
+	}
+}
+
+// flush encode and write queued changes, and sync file in AOFSyncAlways.
+// param c - cache
+func (l *aofLog[K, V]) flush(c *cache[K, V]) {
+	l.mu.Lock()
+	entries, to := l.queue, l.queued
+	l.queue = nil
+	l.mu.Unlock()
+	if len(entries) == 0 {
+		return
+	}
+
+	var err error
+	for _, entry := range entries {
+		record, eerr := c.encodeAOFEntry(entry)
+		if eerr == nil {
+			var n int
+			n, eerr = l.w.Write(record)
+			l.size += int64(n)
+		}
+		if eerr != nil && err == nil {
+			err = fmt.Errorf("append-only file write key = %v: %w", entry.item.Key, eerr)
+		}
+	}
+	if l.option.AOFSync == AOFSyncAlways {
+		if serr := l.sync(); serr != nil && err == nil {
+			err = fmt.Errorf("append-only file sync: %w", serr)
+		}
+	} else if ferr := l.w.Flush(); ferr != nil && err == nil {
+		err = fmt.Errorf("append-only file write: %w", ferr)
+	}
+
+	l.mu.Lock()
+	l.written = to
+	if err != nil {
+		l.failedFrom, l.failedTo, l.failed = to - uint64(len(entries)) + 1, to, err
+	}
+	l.mu.Unlock()
+	l.cond.Broadcast()
+	if err != nil {
+		l.fail(err)
+	}
+}
+
+// fail report Error of append-only file.
+func (l *aofLog[K, V]) fail(err error) {
+	Warn("%v", err)
+	if l.option.OnAOFError != nil {
+		l.option.OnAOFError(err)
+	}
+}
+
+// encodeAOFEntry encode queued change to record.
+// param entry - change of item
+// return arg1 - encoded record
+// return arg2 - Error
+func (c *cache[K, V]) encodeAOFEntry(entry aofEntry[K, V]) ([]byte, error) {
+	// value of deleted item is not needed to replay
+	aof := aofRecord[K]{Op: aofDel, Item: itemRecord[K]{Key: entry.item.Key}}
+	if !entry.deleted {
+		itemRecord, err := c.newItemRecord(entry.item)
+		if err != nil {
+			return nil, err
+		}
+		aof = aofRecord[K]{Op: aofSet, Item: itemRecord}
+	}
+	return encodeAOFRecord(aof, c.option.KeyProvider)
+}
+
+// sync flush buffer, and fsync file unless AOFSyncNever.
+func (l *aofLog[K, V]) sync() error {
+	if err := l.w.Flush(); err != nil {
+		return err
+	}
+	if l.option.AOFSync == AOFSyncNever {
+		return nil
+	}
+	return l.file.Sync()
+}
+
+func (l *aofLog[K, V]) needRewrite() bool {
+	minSize := l.option.AOFRewriteMinSize
+	if minSize <= 0 {
+		minSize = DefaultAOFRewriteMinSize
+	}
+	return l.size >= minSize && l.size >= 2 * l.base
+}
+
+// rewrite write current items to temporary file, and replace file with it.
+// This is called by background goroutine, so changes are queued while rewriting, and written after the items.
+// Changes queued before the snapshot are also written after it, but they are applied in order and end in the same items.
+// param c - cache
+// return arg1 - Error
+func (l *aofLog[K, V]) rewrite(c *cache[K, V]) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	var size int64
+	for _, item := range c.snapshot() {
+		record, err := c.encodeAOFEntry(aofEntry[K, V]{item: item})
+		if err != nil {
+			return fmt.Errorf("encode key = %v: %w", item.Key, err)
+		}
+		n, err := w.Write(record)
+		size += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = l.w.Flush(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), l.path); err != nil {
+		return err
+	}
+	l.file.Close()
+	l.file, l.w = tmp, bufio.NewWriter(tmp)
+	Debug("rewrote append-only file. size = %d -> %d", l.size, size)
+	l.size, l.base = size, size
+	return nil
+}
+
+// encodeAOFRecord encode record with its length and crc32.
+// param record - record
+// param keys - provider of keys, nil if record is not encrypted
+// return arg1 - encoded record
+// return arg2 - Error
+func encodeAOFRecord[K comparable](record aofRecord[K], keys KeyProvider) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, aofHeaderSize))
+	if keys == nil {
+		buf.WriteByte(aofPlain)
+		if err := gob.NewEncoder(&buf).Encode(&record); err != nil {
+			return nil, err
+		}
+	} else {
+		encrypted, err := encryptRecord(keys, &record)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(aofEncrypted)
+		buf.Write(encrypted)
+	}
+	b := buf.Bytes()
+	payload := b[aofHeaderSize:]
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(b[4:8], crc32.ChecksumIEEE(payload))
+	return b, nil
+}
+
+// decodeAOFRecord decode payload of record.
+// param payload - payload of record
+// param keys - provider of keys to decrypt record
+// param record - pointer to record
+// return arg1 - Error
+func decodeAOFRecord[K comparable](payload []byte, keys KeyProvider, record *aofRecord[K]) error {
+	if len(payload) == 0 {
+		return errors.New("record is empty")
+	}
+	switch payload[0] {
+	case aofPlain:
+		return gob.NewDecoder(bytes.NewReader(payload[1:])).Decode(record)
+	case aofEncrypted:
+		return decryptRecord(keys, payload[1:], record)
+	default:
+		return fmt.Errorf("format of record is invalid. format = %d", payload[0])
+	}
+}
+
+// readAOFRecord read record, and check its crc32.
+// return arg1 - payload of record
+// return arg2 - size of record
+// return arg3 - io.EOF at the end of file, or Error of torn record
+func readAOFRecord(r io.Reader) ([]byte, int64, error) {
+	header := make([]byte, aofHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, 0, errors.New("record header is torn")
+		}
+		return nil, 0, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > aofMaxRecordSize {
+		return nil, 0, errors.New("length of record is invalid")
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, 0, errors.New("record is torn")
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, 0, errors.New("checksum of record is invalid")
+	}
+	return payload, int64(aofHeaderSize + len(payload)), nil
+}
@@ -0,0 +1,216 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fileSize get size of file.
+func fileSize(t *testing.T, path string) int64 {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	return info.Size()
+}
+
+func TestOK_AOF_Replay(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := New(Option{Eviction: EvictLRU})
+	if err := c.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	c.Set("key1", "value1", time.Duration(10) * time.Second)
+	c.Set("key1", "value2", time.Duration(10) * time.Second)
+	c.Set("key2", "value", time.Duration(10) * time.Second)
+	c.Del("key2")
+	c.Set("key3", "value", time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size()
+	c.Set("key4", "value", time.Duration(10) * time.Second) // key1 is evicted
+	if err := c.Close(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	r := New(Option{})
+	if err := r.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer r.Close()
+	for _, key := range []string{"key1", "key2"} {
+		if _, found := r.GetItem(key); found {
+			t.Errorf("key(%v) is replayed.", key)
+		}
+	}
+	for _, key := range []string{"key3", "key4"} {
+		if _, found := r.GetItem(key); !found {
+			t.Errorf("key(%v) is not replayed.", key)
+		}
+	}
+
+	// already opened
+	if err := r.OpenAOF(path); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
+
+func TestOK_AOF_Torn(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := New(Option{AOFSync: AOFSyncAlways})
+	c.OpenAOF(path)
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	if fileSize(t, path) == 0 {
+		t.Errorf("record is not synced.")
+	}
+	c.Close()
+	size := fileSize(t, path)
+
+	// crash while writing record
+	f, _ := os.OpenFile(path, os.O_APPEND | os.O_WRONLY, 0644)
+	f.Write([]byte{0, 0, 0, 100, 1, 2, 3})
+	f.Close()
+
+	r := New(Option{})
+	if err := r.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if _, found := r.GetItem("key1"); !found {
+		t.Errorf("key1 is not replayed.")
+	}
+	if fileSize(t, path) != size {
+		t.Errorf("torn record is not truncated.")
+	}
+	r.Set("key2", "value", time.Duration(10) * time.Second)
+	r.Close()
+
+	n := New(Option{})
+	n.OpenAOF(path)
+	defer n.Close()
+	if len(n.List()) != 2 {
+		t.Errorf("keys(%v) are invalid.", n.List())
+	}
+}
+
+func TestOK_AOF_Rewrite(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewTyped[string, int](TypedOption[string, int]{Shards: 4})
+	c.OpenAOF(path)
+	for i := 0; i < 100; i++ {
+		c.Set("key", i, time.Duration(10) * time.Second)
+	}
+
+	// set while rewriting
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 100; i++ {
+			c.Set("other", i, time.Duration(10) * time.Second)
+		}
+	}()
+	if err := c.RewriteAOF(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	wg.Wait()
+	c.Close()
+
+	r := NewTyped[string, int](TypedOption[string, int]{})
+	r.OpenAOF(path)
+	defer r.Close()
+	if v, _ := r.Get("key"); v != 99 {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	if v, _ := r.Get("other"); v != 99 {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("files(%d) are invalid.", len(files))
+	}
+}
+
+func TestOK_AOF_AutoRewrite(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := New(Option{AOFSync: AOFSyncAlways, AOFRewriteMinSize: 1})
+	c.OpenAOF(path)
+	defer c.Close()
+	for i := 0; i < 100; i++ {
+		c.Set("key", i, time.Duration(10) * time.Second)
+	}
+	size := fileSize(t, path)
+	time.Sleep(time.Duration(1500) * time.Millisecond)
+
+	// 100 records are rewritten to 1 record
+	if rewritten := fileSize(t, path); rewritten * 50 > size {
+		t.Errorf("size(%d) is not rewritten. before = %d", rewritten, size)
+	}
+}
+
+func TestNG_AOF(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	if err := c.RewriteAOF(); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if err := c.OpenAOF(filepath.Join(t.TempDir(), "none", "cache.aof")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
+
+func TestNG_AOF_Error(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	var mu sync.Mutex
+	var errs []error
+	c := NewTyped[string, int](TypedOption[string, int]{
+		AOFSync: AOFSyncAlways,
+		OnAOFError: func(err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			errs = append(errs, err)
+		},
+	})
+	if err := c.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if err := c.Set("key1", 1, time.Duration(10) * time.Second); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+
+	// file is broken
+	c.aof.Load().file.Close()
+	if err := c.Set("key2", 2, time.Duration(10) * time.Second); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.GetItem("key2"); !found {
+		t.Errorf("key2 is not set.")
+	}
+	if err := c.Del("key1"); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	mu.Lock()
+	if len(errs) < 2 {
+		t.Errorf("errs(%v) are invalid.", errs)
+	}
+	mu.Unlock()
+	c.Close()
+}
@@ -0,0 +1,183 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// list of arcPolicy
+const (
+	arcT1 = iota // resident items accessed once
+	arcT2 // resident items accessed more than once
+	arcB1 // ghost keys evicted from T1
+	arcB2 // ghost keys evicted from T2
+)
+
+// arcPolicy evict items by Adaptive Replacement Cache.
+// Recently used items are kept in T1, and frequently used items are kept in T2.
+// Keys evicted from T1 and T2 are remembered in ghost lists B1 and B2,
+// and hit of ghost key adapts the target size of T1, so that the balance of recency and frequency follows the access pattern.
+// All sizes are in bytes, and the size of cache is ThresholdSize.
+type arcPolicy[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	lists [4]*list.List // *arcEntry, front is most recently used
+	sizes [4]int
+	elements map[K]*list.Element
+	target int // target size of T1
+	newest *TypedItem[K, V]
+}
+
+type arcEntry[K comparable, V any] struct {
+	key K
+	size int
+	item *TypedItem[K, V] // nil in ghost lists
+	list int
+}
+
+func newARCPolicy[K comparable, V any](opt *TypedOption[K, V]) *arcPolicy[K, V] {
+	p := &arcPolicy[K, V]{
+		option: opt,
+		elements: map[K]*list.Element{},
+	}
+	for i := range p.lists {
+		p.lists[i] = list.New()
+	}
+	return p
+}
+
+func (p *arcPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	to := arcT1
+	e, found := p.elements[item.Key]
+	if found {
+		entry := e.Value.(*arcEntry[K, V])
+		switch entry.list {
+		case arcB1:
+			// recency is in demand
+			p.target = min(p.target + p.delta(arcB2, arcB1, item.size), p.option.ThresholdSize)
+			to = arcT2
+		case arcB2:
+			// frequency is in demand
+			p.target = max(p.target - p.delta(arcB1, arcB2, item.size), 0)
+			to = arcT2
+		}
+		p.remove(e)
+	} else {
+		p.trim(item.size)
+	}
+	p.push(&arcEntry[K, V]{key: item.Key, size: item.size, item: item, list: to})
+	p.newest = item
+}
+
+func (p *arcPolicy[K, V]) Access(item *TypedItem[K, V]) {
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*arcEntry[K, V])
+	switch entry.list {
+	case arcT1:
+		p.move(e, arcT2)
+	case arcT2:
+		p.lists[arcT2].MoveToFront(e)
+	}
+}
+
+func (p *arcPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+	if e, found := p.elements[item.Key]; found {
+		entry := e.Value.(*arcEntry[K, V])
+		if entry.list == arcT1 || entry.list == arcT2 {
+			p.remove(e)
+		}
+	}
+	if p.newest == item {
+		p.newest = nil
+	}
+}
+
+func (p *arcPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	order = make([]*TypedItem[K, V], 0, p.lists[arcT1].Len() + p.lists[arcT2].Len())
+	for _, l := range []int{arcT1, arcT2} {
+		for e := p.lists[l].Back(); e != nil; e = e.Prev() {
+			order = append(order, e.Value.(*arcEntry[K, V]).item)
+		}
+	}
+	return nil, order
+}
+
+// Victim get item to be evicted, and remember its key in ghost list.
+// The newest item is regarded as not inserted yet, as REPLACE of ARC is done before insertion.
+func (p *arcPolicy[K, V]) Victim() (item *TypedItem[K, V], found bool) {
+	t1 := p.lists[arcT1].Back()
+	t1Size := p.sizes[arcT1]
+	if p.newest != nil {
+		if e, found := p.elements[p.newest.Key]; found && e.Value.(*arcEntry[K, V]).list == arcT1 {
+			t1Size -= p.newest.size
+			if t1 == e {
+				t1 = nil
+			}
+		}
+	}
+	t2 := p.lists[arcT2].Back()
+
+	var e *list.Element
+	switch {
+	case t1 != nil && (t1Size > p.target || t2 == nil):
+		e = t1
+	case t2 != nil:
+		e = t2
+	default:
+		e = p.lists[arcT1].Back()
+	}
+	if e == nil {
+		return nil, false
+	}
+
+	entry := e.Value.(*arcEntry[K, V])
+	item = entry.item
+	if entry.list == arcT1 {
+		p.move(e, arcB1)
+	} else {
+		p.move(e, arcB2)
+	}
+	entry.item = nil
+	return item, true
+}
+
+// delta get the amount of adaptation for the hit of ghost key.
+func (p *arcPolicy[K, V]) delta(other, hit int, size int) int {
+	if p.sizes[hit] == 0 || p.sizes[other] <= p.sizes[hit] {
+		return size
+	}
+	return p.sizes[other] / p.sizes[hit] * size
+}
+
+// trim drop ghost keys before new item is inserted,
+// so that T1+B1 and all lists are kept within the size of cache and twice of it.
+// param size - size of new item
+func (p *arcPolicy[K, V]) trim(size int) {
+	threshold := p.option.ThresholdSize
+	for p.sizes[arcT1] + p.sizes[arcB1] + size > threshold && p.lists[arcB1].Len() > 0 {
+		p.remove(p.lists[arcB1].Back())
+	}
+	for p.sizes[arcT1] + p.sizes[arcT2] + p.sizes[arcB1] + p.sizes[arcB2] + size > 2 * threshold && p.lists[arcB2].Len() > 0 {
+		p.remove(p.lists[arcB2].Back())
+	}
+}
+
+func (p *arcPolicy[K, V]) push(entry *arcEntry[K, V]) {
+	p.elements[entry.key] = p.lists[entry.list].PushFront(entry)
+	p.sizes[entry.list] += entry.size
+}
+
+func (p *arcPolicy[K, V]) remove(e *list.Element) *arcEntry[K, V] {
+	entry := e.Value.(*arcEntry[K, V])
+	p.lists[entry.list].Remove(e)
+	p.sizes[entry.list] -= entry.size
+	delete(p.elements, entry.key)
+	return entry
+}
+
+func (p *arcPolicy[K, V]) move(e *list.Element, l int) {
+	entry := p.remove(e)
+	entry.list = l
+	p.push(entry)
+}
@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOK_ARC_ScanResistance(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictARC,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size() * 4 + c.Size() / 2
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+	for _, key := range []string{"key1", "key2", "key3", "key4"} {
+		_, _ = c.Get(key)
+	}
+
+	// one-off items evict each other after key1
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("scan%d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for _, key := range []string{"key2", "key3", "key4", "scan9"} {
+		if _, found := c.GetItem(key); !found {
+			t.Errorf("key(%v) is evicted.", key)
+		}
+	}
+	if len(c.List()) != 4 {
+		t.Errorf("items(%v) is invalid. expected = %d items", c.List(), 4)
+	}
+
+	p := c.shards[0].policy.(*arcPolicy[string, int64])
+	if p.lists[arcB1].Len() == 0 || p.lists[arcB2].Len() != 1 {
+		t.Errorf("ghost lists(%d, %d) are invalid.", p.lists[arcB1].Len(), p.lists[arcB2].Len())
+	}
+}
+
+func TestOK_ARC_Adaptation(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictARC,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size() * 2 + c.Size() / 2
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second) // key1 is evicted to B1
+	p := c.shards[0].policy.(*arcPolicy[string, int64])
+	if p.target != 0 {
+		t.Errorf("target(%d) is invalid. expected = %d", p.target, 0)
+	}
+
+	// hit of B1 grows T1
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	if p.target == 0 {
+		t.Errorf("target(%d) is not grown.", p.target)
+	}
+	e := p.elements["key1"]
+	if e.Value.(*arcEntry[string, int64]).list != arcT2 {
+		t.Errorf("key(%v) is not in T2.", "key1")
+	}
+
+	// hit of B2 shrinks T1
+	grown := p.target
+	for _, key := range []string{"key2", "key3"} {
+		_, _ = c.Get(key)
+	}
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+	c.Set("key5", 5, time.Duration(10) * time.Second)
+	for key, e := range p.elements {
+		if entry := e.Value.(*arcEntry[string, int64]); entry.list == arcB2 {
+			c.Set(key, 0, time.Duration(10) * time.Second)
+			break
+		}
+	}
+	if p.target >= grown {
+		t.Errorf("target(%d) is not shrunk from %d.", p.target, grown)
+	}
+	if c.Size() > c.option.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), c.option.ThresholdSize)
+	}
+}
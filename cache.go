@@ -1,10 +1,10 @@
 package cache
 
 import (
+	"hash/maphash"
 	"sync"
 	"sync/atomic"
 	"time"
-	"sort"
 	"reflect"
 	"errors"
 )
@@ -14,63 +14,181 @@ const (
 	DefaultExpiration time.Duration = 60 * 60 * time.Second; // ns 1h
 )
 
+const (
+	keyLockCount = 256 // number of locks of keys to serialize writing to Writer and storing
+)
+
 // Cache class
+// Cache is untyped cache, which is a thin wrapper of TypedCache[string, interface{}].
 type Cache struct {
-	*cache
+	*TypedCache[string, interface{}]
 }
 
+// Item item of Cache
+type Item = TypedItem[string, interface{}]
+
 // New create instance only once.
 // param opt - option
 // return arg1 - instance of Cache
 func New(opt Option) *Cache {
-	c := &cache{
-			items:	map[string]*Item{},
+	c := &Cache{NewTyped[string, interface{}](opt)}
+	c.validate = func(value interface{}) error {
+		supported, kind := c.IsSupported(value)
+		if !supported {
+			return errors.New("type of value is not supported. type = " + kind)
+		}
+		return nil
+	}
+	return c
+}
+
+// TypedCache class
+// TypedCache is type-safe cache of value V by key K.
+type TypedCache[K comparable, V any] struct {
+	*cache[K, V]
+}
+
+// NewTyped create instance only once.
+// param opt - option
+// return arg1 - instance of TypedCache
+func NewTyped[K comparable, V any](opt TypedOption[K, V]) *TypedCache[K, V] {
+	n := opt.Shards
+	if n < 1 {
+		n = 1
+	}
+	c := &cache[K, V]{
+			shards:	make([]*shard[K, V], n),
+			seed:	maphash.MakeSeed(),
 			option: &opt,
 	}
-	return &Cache{c}
+	c.codec = newValueCodec(c.option)
+	for i := range c.shards {
+		shardOpt := c.option
+		if n > 1 {
+			// ThresholdSize is split into shards
+			copied := *c.option
+			copied.ThresholdSize = shardThreshold(c.option.ThresholdSize, n, i)
+			shardOpt = &copied
+		}
+		c.shards[i] = newShard(shardOpt, c.notify, c.journal)
+	}
+	if opt.Writer != nil && opt.WriteMode == WriteBehind {
+		c.behind = newWriteBehind(c.option)
+	}
+	return &TypedCache[K, V]{c}
+}
+
+// shardThreshold get share of ThresholdSize for shard.
+// Remainder is spread into first shards, and share is at least 1 so that shard is not unlimited.
+// param threshold - ThresholdSize of cache
+// param n - number of shards
+// param i - index of shard
+// return arg1 - ThresholdSize of shard
+func shardThreshold(threshold int, n int, i int) int {
+	if threshold <= 0 {
+		return threshold
+	}
+	share := threshold / n
+	if i < threshold % n {
+		share++
+	}
+	if share < 1 {
+		share = 1
+	}
+	return share
 }
 
-type cache struct {
-	sync.RWMutex
-	items map[string]*Item
-	option *Option
+type cache[K comparable, V any] struct {
+	shards []*shard[K, V]
+	seed maphash.Seed
+	option *TypedOption[K, V]
 	optimizer *Optimizer
-	size int
+	loads loadGroup[K, V]
+	behind *writeBehind[K, V] // queue of writes in WriteBehind
+	onEvict atomic.Pointer[func(key K, value V, reason EvictionReason)]
+	watchers watchers[K, V]
+	stats statsCounter
+	aof atomic.Pointer[aofLog[K, V]]
+	codec Codec // codec of value
+	keyLocks [keyLockCount]sync.Mutex // serialize writing to Writer and storing of same key
+	validate func(value V) error // check value before set
 }
 
-// Option option
-type Option struct {
+// Option option of Cache
+type Option = TypedOption[string, interface{}]
+
+// TypedOption option of TypedCache
+type TypedOption[K comparable, V any] struct {
 	ThresholdSize int // default is 0(unlimited)
 	ThresholdAccess time.Duration // default is 0(not care)
 	ThresholdAccessCount int64 // default is 0(not care)
+	Eviction EvictionMode // default is EvictPriority
+	DecayInterval time.Duration // default is 0(frequency is halved on every Optimize in EvictLFU)
+	Policy func() EvictionPolicy[K, V] // default is nil(policy of Eviction), called for each shard
+	Shards int // default is 0(not sharded)
+	Loader Loader[K, V] // default is nil(items are not loaded by Get)
+	RefreshAhead float64 // default is 0(not refreshed), fraction of expire time in which accessed item is refreshed by Loader
+	StaleWhileRevalidate time.Duration // default is 0(not served), period after expiration in which stale item is served and reloaded by Loader
+	StaleIfError time.Duration // default is 0(not served), period after expiration in which stale item is served while reloading fails
+	Writer Writer[K, V] // default is nil(Set and Del are not written to backing store)
+	WriteMode WriteMode // default is WriteThrough
+	WriteInterval time.Duration // default is DefaultWriteInterval, interval of flushing in WriteBehind
+	WriteBatchSize int // default is 0(flushed only on WriteInterval), number of queued writes to flush in WriteBehind
+	WriteRetries int // default is 0(not retried), retries of failed write in WriteBehind
+	WriteRetryInterval time.Duration // default is DefaultWriteRetryInterval, interval before first retry in WriteBehind, which is doubled on each retry
+	OnWriteError func(key K, err error) // default is nil, called when write is failed after retries in WriteBehind
+	WatchBuffer int // default is DefaultWatchBuffer, buffer size of channel of Watch
+	WatchOverflow OverflowPolicy // default is OverflowDrop
+	AOFSync AOFSyncPolicy // default is AOFSyncEverySecond
+	AOFRewriteMinSize int64 // default is DefaultAOFRewriteMinSize, minimum size of append-only file to be rewritten
+	OnAOFError func(err error) // default is nil, called when writing, sync or rewrite of append-only file is failed
+	Codec Codec // default is GobCodec, codec of value in snapshot and append-only file
+	Registry *Registry // default is DefaultRegistry, registry of concrete types of value of interface type
+	Compressor Compressor // default is nil, values are not compressed
+	CompressMinSize int // default is DefaultCompressMinSize, minimum size of encoded value to be compressed
+	KeyProvider KeyProvider // default is nil, values, snapshot and append-only file are not encrypted
 }
 
-// Set set item to cache.
+// stalePeriod get period after expiration in which item is kept as stale.
+func (o *TypedOption[K, V]) stalePeriod() time.Duration {
+	return max(o.StaleWhileRevalidate, o.StaleIfError)
+}
+
+// Get get value from cache.
 // param key - key of item
-// param value - value of item
-// param expireIn - expire time
-// return arg1 - Error
-func (c *cache) Set(key string, value interface{}, expireIn time.Duration) error {
-	supported, kind := c.IsSupported(value)
-	if !supported {
-		return errors.New("type of value is not supported. type = " + kind)
-	}
-	c.Lock()
-	now := time.Now()
-	var time time.Time
-	if expireIn > 0 {
-		time = now.Add(expireIn)
-	} else {
-		time = now.Add(DefaultExpiration)
+// return arg1 - pointer to value of item
+// return arg2 - If item is found, return true.
+func (c *Cache) Get(key string) (value *interface{}, found bool) {
+	value, found, _ = c.GetStale(key)
+	return value, found
+}
+
+// GetStale get value from cache, and whether it is stale.
+// param key - key of item
+// return arg1 - pointer to value of item
+// return arg2 - If item is found, return true.
+// return arg3 - If item is expired and served as stale, return true.
+func (c *Cache) GetStale(key string) (value *interface{}, found bool, stale bool) {
+	item, found, stale := c.getItem(key)
+	if found && item.encoded() == nil {
+		value = &item.Object
+	} else if found {
+		object := c.value(item)
+		value = &object
 	}
-	item := &Item{key, value, 0, &time, 0, nil}
-	item.Priority = c.priority(item)
-	c.set(key, item)
-	c.Unlock()
-	return nil
+	return value, found, stale
 }
 
-func (c *cache) IsSupported(obj interface{}) (bool, string) {
+// IsSupported check type of value.
+// Nil, channel and function can not be cached.
+// param obj - value of item
+// return arg1 - If type of value is supported, return true.
+// return arg2 - kind of value
+func (c *Cache) IsSupported(obj interface{}) (bool, string) {
+	if obj == nil {
+		Warn("nil is unsupported.")
+		return false, "nil"
+	}
 	kind := reflect.TypeOf(obj).Kind()
 	if kind == reflect.Chan || kind == reflect.Func {
 		Warn("%s is unsupported.", kind);
@@ -80,194 +198,269 @@ func (c *cache) IsSupported(obj interface{}) (bool, string) {
 	return true, kind.String();
 }
 
-func (c *cache) SizeOfItem(item *Item) int {
-	
-	size := c.SizeOf(item.PriorityThan)
-	size += c.SizeOf(item.AccessCount)
-	size += c.SizeOf(item.Expiration)
-	size += c.SizeOf(item.Key)
-	size += c.SizeOf(item.LastAccess)
-	size += c.SizeOf(item.Object)
-	size += c.SizeOf(item.Priority)
-	return size
+// GetItems get item map from cache.
+// Object of item is zero value if the value is stored in bytes, which is compressed or encrypted.
+// Use Value to get value of item.
+// return arg1 - item map, which is a copy of items in all shards
+func (c *TypedCache[K, V]) GetItems() map[K]*TypedItem[K, V] {
+	items := map[K]*TypedItem[K, V]{}
+	for _, s := range c.shards {
+		s.RLock()
+		for key, item := range s.items {
+			items[key] = item
+		}
+		s.RUnlock()
+	}
+	return items
 }
 
-func (c *cache) SizeOf(obj interface{}) int {
-	t := reflect.TypeOf(obj)
-	var o interface{}
-	if t.Kind() == reflect.Ptr {
-		v := reflect.ValueOf(obj)
-		o = v.Elem()
-	} else {
-		o = obj
+// Set set item to cache.
+// If Writer is set in option, item is also written to backing store.
+// In WriteThrough, item is not set when Writer returns error.
+// In AOFSyncAlways, Set waits until item is synced to append-only file, and returns its Error after item is set.
+// param key - key of item
+// param value - value of item
+// param expireIn - expire time
+// return arg1 - Error
+func (c *cache[K, V]) Set(key K, value V, expireIn time.Duration) error {
+	if err := c.check(value); err != nil {
+		return err
 	}
-	t = reflect.TypeOf(o)
-	
-	switch t.Kind() {
-	case reflect.Slice, reflect.Array, reflect.Map:
-		v := reflect.ValueOf(o)
-		elmSize := int(t.Elem().Size())
-		elmNum := v.Len()
-		return elmSize * elmNum
-	case reflect.String:
-		v := reflect.ValueOf(o)
-		return v.Len()
-	default:
-		if t.Kind() == reflect.Struct {
-			Debug("the case of a struct, size may not be correct. size is top level size of struct.");
-		}
-		return int(t.Size());
+	item, err := c.newItem(key, value, expireIn)
+	if err != nil {
+		return err
+	}
+	if c.option.Writer == nil {
+		c.store(item)
+		return c.syncAOF()
+	}
+
+	// write and store of same key are serialized, so that backing store and cache end with same value
+	lock := c.keyLock(key)
+	lock.Lock()
+	if err := c.write(key, value, false); err != nil {
+		lock.Unlock()
+		return err
 	}
+	s := c.shard(key)
+	old, evicted := s.storeDeferred(item)
+	lock.Unlock()
+	s.notifyEvicted(evicted)
+	c.emitSet(old, item)
+	return c.syncAOF()
 }
 
-func (c *cache) set(key string, item *Item) {
-	beforeItemSize := 0
-	if c.items[key] != nil {
-		beforeItem := c.items[key]
-		beforeItemSize = c.SizeOfItem(beforeItem)
+// check check value before set.
+func (c *cache[K, V]) check(value V) error {
+	if c.validate != nil {
+		return c.validate(value)
 	}
-	c.items[key] = item
-	c.size = c.size - beforeItemSize + c.SizeOfItem(item)
+	return nil
 }
 
-func (c *cache) Get(key string) (value *interface{}, found bool) {
-	c.RLock()
-	now := time.Now()
-	item, found := c.get(key)
-	if found {
-		item.AccessCount = atomic.AddInt64(&item.AccessCount, 1)
-		item.LastAccess = &now
-		value = &item.Object
+// put set item to cache without writing to backing store.
+// param key - key of item
+// param value - value of item, which is checked
+// param expireIn - expire time
+// return arg1 - Error of encoding value
+func (c *cache[K, V]) put(key K, value V, expireIn time.Duration) error {
+	item, err := c.newItem(key, value, expireIn)
+	if err != nil {
+		return err
 	}
-	c.RUnlock()
-	return value, found
+	c.store(item)
+	return nil
 }
 
-// test case only
-func (c *cache) GetItem(key string) (item *Item, found bool) {
-	c.RLock()
-	item, found = c.get(key)
-	c.RUnlock()
-	return item, found
+// store set item to shard, and send event.
+// param item - item whose size is set
+func (c *cache[K, V]) store(item *TypedItem[K, V]) {
+	old := c.shard(item.Key).store(item)
+	c.emitSet(old, item)
 }
 
-// private function
-func (c *cache) get(key string) (item *Item, found bool) {
-	item = c.items[key]
-	if item != nil {
-		found = true
+// newItem create item whose value is encoded and size is set.
+// param key - key of item
+// param value - value of item
+// param expireIn - expire time
+// return arg1 - item
+// return arg2 - Error of encoding value
+func (c *cache[K, V]) newItem(key K, value V, expireIn time.Duration) (*TypedItem[K, V], error) {
+	if expireIn <= 0 {
+		expireIn = DefaultExpiration
 	}
-	
-	return item, found
+	expiration := time.Now().Add(expireIn)
+	item := &TypedItem[K, V]{Key: key, Object: value, Expiration: &expiration, ttl: expireIn}
+	if err := c.encode(item); err != nil {
+		return nil, err
+	}
+	item.size = c.SizeOfItem(item)
+	return item, nil
 }
 
-func (c *cache) Del(key string) {
-	c.Lock()
-	c.del(key)
-	c.Unlock()
+// shard get shard of key.
+// param key - key of item
+// return arg1 - shard
+func (c *cache[K, V]) shard(key K) *shard[K, V] {
+	if len(c.shards) == 1 {
+		return c.shards[0]
+	}
+	return c.shards[maphash.Comparable(c.seed, key) % uint64(len(c.shards))]
 }
 
-func (c *cache) del(key string) {
-	item, found := c.get(key)
-	size := 0
-	if found {
-		size = c.SizeOfItem(item)
+// SizeOfItem get size of item, which is the size of key, value and metadata of item.
+// Value stored in bytes is counted by the size of bytes.
+// param item - item
+// return arg1 - size of item in bytes
+func (c *cache[K, V]) SizeOfItem(item *TypedItem[K, V]) int {
+	size := itemMetaSize
+	size += c.SizeOf(item.Key)
+	if data := item.encoded(); data != nil {
+		size += len(data)
+	} else {
+		size += c.SizeOf(item.Object)
 	}
-	delete(c.items, key)
-	//cache size
-	c.size = c.size - size
+	return size
 }
 
-// List of fineName in Cache
-func (c *cache) List() []string {
-	names := make([]string, len(c.items))
+// SizeOf get size of value recursively.
+// Pointers, slices, maps, strings and struct fields are walked, and pointers shared or cyclic are counted once.
+// Value which implements Sizer reports its own size.
+// param obj - value
+// return arg1 - size of value in bytes
+func (c *cache[K, V]) SizeOf(obj interface{}) int {
+	var s sizer
+	return s.sizeOf(reflect.ValueOf(obj))
+}
+
+// Get get value from cache.
+// If Loader is set in option, item which is not found is loaded.
+// param key - key of item
+// return arg1 - value of item
+// return arg2 - If item is found, return true.
+func (c *cache[K, V]) Get(key K) (value V, found bool) {
+	value, found, _ = c.GetStale(key)
+	return value, found
+}
 
-	i := 0
-	for key := range c.items {
-		names[i] = key
-		i ++
+// GetStale get value from cache, and whether it is stale.
+// Within StaleWhileRevalidate after expiration, stale value is returned and reloaded by Loader in background.
+// While reloading fails, stale value is returned within StaleIfError after expiration.
+// param key - key of item
+// return arg1 - value of item
+// return arg2 - If item is found, return true.
+// return arg3 - If item is expired and served as stale, return true.
+func (c *cache[K, V]) GetStale(key K) (value V, found bool, stale bool) {
+	item, found, stale := c.getItem(key)
+	if found {
+		value = c.value(item)
 	}
+	return value, found, stale
+}
 
-	return names
+// access get item and update access information of item.
+func (c *cache[K, V]) access(key K) (item *TypedItem[K, V], found bool) {
+	return c.shard(key).access(key)
 }
 
-func (c *cache) Priority(key string) int {
-	item, _ := c.GetItem(key)
-	return c.priority(item)
+// test case only
+// Object of item is zero value if the value is stored in bytes as GetItems.
+func (c *cache[K, V]) GetItem(key K) (item *TypedItem[K, V], found bool) {
+	return c.shard(key).lookup(key)
 }
 
-func (c *cache) priority(item *Item) int {
-	now := time.Now()
-	priority := 0 //Items to be deleted
-	if item == nil {
-		return priority
+// Del delete item from cache.
+// If Writer is set in option, key is also deleted from backing store.
+// In WriteThrough, item is not deleted when Writer returns error.
+// In AOFSyncAlways, Del waits until deletion is synced to append-only file as Set.
+// param key - key of item
+// return arg1 - Error of Writer or append-only file
+func (c *cache[K, V]) Del(key K) error {
+	if c.option.Writer == nil {
+		c.shard(key).remove(key)
+		return c.syncAOF()
 	}
-	
-	// no expiration
-	if item.Expiration == nil || item.Expiration.IsZero() {
-		priority ++
+
+	// write and remove of same key are serialized as Set
+	var zero V
+	lock := c.keyLock(key)
+	lock.Lock()
+	if err := c.write(key, zero, true); err != nil {
+		lock.Unlock()
+		return err
 	}
-	// expiration > now
-	if item.Expiration != nil && item.Expiration.After(now) {
-		priority ++
+	s := c.shard(key)
+	evicted := s.removeDeferred(key)
+	lock.Unlock()
+	s.notifyEvicted(evicted)
+	return c.syncAOF()
+}
+
+// keyLock get lock to serialize writing to Writer and storing of key.
+// Keys are striped into keyLockCount locks.
+// param key - key of item
+// return arg1 - lock of key
+func (c *cache[K, V]) keyLock(key K) *sync.Mutex {
+	return &c.keyLocks[maphash.Comparable(c.seed, key) % keyLockCount]
+}
+
+// List of fineName in Cache
+// Expired items are deleted and not listed.
+func (c *cache[K, V]) List() []K {
+	names := []K{}
+	for _, s := range c.shards {
+		names = s.list(names)
 	}
-	// last access + threshold > now
-	if c.option.ThresholdAccess != 0 && item.LastAccess != nil && item.LastAccess.Before(now) && item.LastAccess.Add(c.option.ThresholdAccess).After(now) {
-		priority ++
+
+	return names
+}
+
+// Priority get priority of item by default policy.
+// param key - key of item
+// return arg1 - priority (0 is to be deleted)
+func (c *cache[K, V]) Priority(key K) int {
+	item, _ := c.GetItem(key)
+	if p, ok := c.shard(key).policy.(*priorityPolicy[K, V]); ok {
+		return p.priority(item)
 	}
-	// access count >= threshold
-	if c.option.ThresholdAccessCount != 0 && item.AccessCount >= c.option.ThresholdAccessCount {
-		priority ++
+	if item == nil {
+		return 0
 	}
-	
-	return priority
+	return int(atomic.LoadInt64(&item.Priority))
 }
 
-func (c *cache) Size() int {
-	return c.size
+// Size get total size of items in all shards.
+// return arg1 - size in bytes
+func (c *cache[K, V]) Size() int {
+	_, size := c.stat()
+	return size
 }
 
-// The optimized by priority
-func (c *cache) Optimize() {
-	Debug("before optimizing. files = %d size = %d bytes", len(c.items), c.size)
-
-	// apply priority
-	tmp := make(sortableItems, 0, len(c.items))
-	for key, item := range c.items {
-		priority := c.priority(item)
-		if priority == 0 {
-			c.Lock()
-			c.del(key)
-			Debug("optimizing delete key = %s", key)
-			c.Unlock()
-		} else {
-			item.Priority = priority
-			tmp = append(tmp, item)
-		}
+// stat get number of items and size of all shards.
+func (c *cache[K, V]) stat() (count int, size int) {
+	for _, s := range c.shards {
+		n, sz := s.stat()
+		count += n
+		size += sz
 	}
-	
-	// sort
-	sort.Sort(tmp)
-	
-	// compaction
-	if 0 < c.option.ThresholdSize && c.size > c.option.ThresholdSize {
-		for i, item := range tmp {
-			if item != nil {
-				c.Lock()
-				key := item.Key
-				tmp[i] = nil
-				c.del(key)
-				Debug("compaction delete key = %s", key)
-				c.Unlock()
-				if c.size <= c.option.ThresholdSize {
-					break
-				}
-			} else {
-				Warn("item is nil")
-			}
-		}
+	return count, size
+}
+
+// The optimized by eviction policy
+// Each shard is optimized to its share of ThresholdSize.
+func (c *cache[K, V]) Optimize() {
+	start := time.Now()
+	count, size := c.stat()
+	Debug("before optimizing. files = %d size = %d bytes", count, size)
+
+	for _, s := range c.shards {
+		s.optimize()
 	}
-	Debug("after optimizing. files = %d size = %d bytes", len(c.items), c.size)
+
+	count, size = c.stat()
+	Debug("after optimizing. files = %d size = %d bytes", count, size)
+	c.stats.optimizations.Add(1)
+	c.stats.optimizeNanos.Add(int64(time.Since(start)))
 }
 
 // RunOptimizer run optimizing
@@ -275,27 +468,25 @@ func (c *cache) Optimize() {
 //  - update priority of item
 //  - delete items of expired
 // param interval - interval of optimize
-func (c *cache) RunOptimizer(interval time.Duration) {
+func (c *cache[K, V]) RunOptimizer(interval time.Duration) {
 	if c.optimizer == nil {
 		c.optimizer = &Optimizer{Interval: interval * time.Second}
 	}
+	// stop channel is made before running, so that StopOptimizer does not race with Run
+	c.optimizer.stop = make(chan bool)
 	
 	go c.optimizer.Run(c)
 }
 
 // StopOptimizer stop optimizing
-func (c *cache) StopOptimizer() {
-	if c.optimizer != nil {
+// Optimizer which is not running is not stopped again.
+func (c *cache[K, V]) StopOptimizer() {
+	if c.optimizer != nil && c.optimizer.stop != nil {
 		c.optimizer.stop <- true
+		c.optimizer.stop = nil
 	}
 }
 
-// GetItems get item map from cache.
-// return arg1 - item map
-func (c *Cache) GetItems() map[string]*Item {
-	return c.items
-}
-
 // Optimizer optimizer optimize cache.
 type Optimizer struct {
 	Interval time.Duration
@@ -303,54 +494,79 @@ type Optimizer struct {
 	stop     chan bool
 }
 
+// optimizable is cache which can be optimized.
+type optimizable interface {
+	Optimize()
+}
+
 // Run run optimizer
 // param c - instance of cache
-func (o *Optimizer) Run(c *cache) {
-	o.stop = make(chan bool)
-	tick := time.Tick(o.Interval)
+func (o *Optimizer) Run(c optimizable) {
+	if o.stop == nil {
+		o.stop = make(chan bool)
+	}
+	stop := o.stop
+	ticker := time.NewTicker(o.Interval)
+	defer ticker.Stop()
 	for {
 		select {
-		case <-tick:
+		case <-ticker.C:
 			if !o.runing {
 				o.runing = true
 				c.Optimize()
 				o.runing = false
 			}
 			break
-		case <-o.stop:
+		case <-stop:
 			return
 		}
 	}
 }
 
-// Item item of cache
-type Item struct {
-	Key string
-	Object interface{}
-	Priority int
+// TypedItem item of cache
+type TypedItem[K comparable, V any] struct {
+	Key K
+	Object V
+	Priority int64 // updated atomically
 	Expiration *time.Time
-	AccessCount int64
-	LastAccess *time.Time
+	AccessCount int64 // updated atomically
+	LastAccess atomic.Pointer[time.Time] // updated atomically
+	size int // size of item when it is set
+	ttl time.Duration // expire time when it is set
+	refreshing int32 // 1 while item is refreshed, updated atomically
+	reloadFailed int32 // 1 after refreshing is failed, updated atomically
+	data atomic.Pointer[[]byte] // encoded value, which is compressed or encrypted. Object is zero value if it is set
+	compressed bool // If data is compressed, true.
+	encrypted bool // If data is encrypted, true.
+}
+
+// encoded get encoded value of item.
+// Encrypted value is swapped atomically when keys are rotated.
+// return arg1 - encoded value, nil if value is stored in Object
+func (i *TypedItem[K, V]) encoded() []byte {
+	if data := i.data.Load(); data != nil {
+		return *data
+	}
+	return nil
+}
+
+// Expired check expiration of item.
+// param now - current time
+// result arg1 - If expiration of item has passed, return true.
+func (i *TypedItem[K, V]) Expired(now time.Time) bool {
+	return i.Expiration != nil && !i.Expiration.IsZero() && !i.Expiration.After(now)
 }
 
 // PriorityThan compare the priority
 // param item - Item
 // result arg1 - If this is higher than item, return true.
-func (i *Item) PriorityThan(item *Item) bool {
-	if i.Priority == item.Priority {
+func (i *TypedItem[K, V]) PriorityThan(item *TypedItem[K, V]) bool {
+	priority, other := atomic.LoadInt64(&i.Priority), atomic.LoadInt64(&item.Priority)
+	if priority == other {
 		if i.Expiration == item.Expiration {
 			return true
 		}
 		return i.Expiration.After(*item.Expiration)
 	}
-	return (i.Priority) > (item.Priority)
-}
-
-type sortableItems []*Item
-func (sitems sortableItems) Len() int           { return len(sitems) }
-func (sitems sortableItems) Less(i, j int) bool {
-	ii := sitems[i]
-	ij := sitems[j]
-	return !ii.PriorityThan(ij)
+	return priority > other
 }
-func (sitems sortableItems) Swap(i, j int)      { sitems[i], sitems[j] = sitems[j], sitems[i] }
@@ -89,8 +89,8 @@ func TestOK_SizeOf_MapByte(t *testing.T) {
 		}
 	c := New(opt)
 	
-	// SizeOf
-	expected := 10;
+	// SizeOf, keys are counted
+	expected := 90;
 	size := c.SizeOf(obj)
 	if size != expected {
 		t.Errorf("size(%d) is invalid. expected = %d", size, expected)
@@ -114,8 +114,8 @@ func TestOK_SizeOf_MapInt32(t *testing.T) {
 		}
 	c := New(opt)
 	
-	// SizeOf
-	expected := 40;
+	// SizeOf, keys are counted
+	expected := 120;
 	size := c.SizeOf(obj)
 	if size != expected {
 		t.Errorf("size(%d) is invalid. expected = %d", size, expected)
@@ -222,6 +222,113 @@ func TestOK_SizeOf_ArrayInt32(t *testing.T) {
 	}
 }
 
+type sizeNode struct {
+	Name string
+	Next *sizeNode
+}
+
+type sizeReported struct {
+	data []byte
+}
+
+func (s sizeReported) Size() int {
+	return 1000
+}
+
+func TestOK_SizeOf_MapString(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// test data
+	obj := map[string]string{"key1": "value1", "key2": "value22"}
+
+	c := New(Option{})
+
+	// SizeOf, keys and contents of strings are counted
+	expected := 4 + 6 + 4 + 7;
+	size := c.SizeOf(obj)
+	if size != expected {
+		t.Errorf("size(%d) is invalid. expected = %d", size, expected)
+	}
+}
+
+func TestOK_SizeOf_Struct(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// test data
+	type value struct {
+		ID int64
+		Name string
+		Tags []string
+		Attrs map[string]interface{}
+		Created time.Time
+	}
+	obj := &value{
+		ID: 1,
+		Name: "tico8",
+		Tags: []string{"a", "bc"},
+		Attrs: map[string]interface{}{"age": int32(20)},
+		Created: time.Now(),
+	}
+
+	c := New(Option{})
+
+	// SizeOf, pointer is followed and fields are walked, time is counted by its size
+	expected := 8 + 5 + (1 + 2) + (3 + 4) + int(reflect.TypeOf(time.Time{}).Size());
+	size := c.SizeOf(obj)
+	if size != expected {
+		t.Errorf("size(%d) is invalid. expected = %d", size, expected)
+	}
+}
+
+func TestOK_SizeOf_Cycle(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// test data
+	node1 := &sizeNode{Name: "node1"}
+	node2 := &sizeNode{Name: "node2", Next: node1}
+	node1.Next = node2
+	shared := []*sizeNode{node1, node1, node2}
+	self := map[string]interface{}{"key": "value"}
+	self["self"] = self
+
+	c := New(Option{})
+
+	// SizeOf, cyclic and shared pointers are counted once
+	if size := c.SizeOf(node1); size != 10 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 10)
+	}
+	if size := c.SizeOf(shared); size != 10 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 10)
+	}
+	if size := c.SizeOf(self); size != 3 + 5 + 4 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 3 + 5 + 4)
+	}
+}
+
+func TestOK_SizeOf_Sizer(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// test data
+	obj := []sizeReported{{data: make([]byte, 10)}, {}}
+
+	c := New(Option{})
+
+	// SizeOf, Sizer reports its own size
+	if size := c.SizeOf(obj); size != 2000 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 2000)
+	}
+	if size := c.SizeOf(map[string]Sizer{"key": sizeReported{}, "nil": nil}); size != 3 + 1000 + 3 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 3 + 1000 + 3)
+	}
+	if size := c.SizeOf(nil); size != 0 {
+		t.Errorf("size(%d) is invalid. expected = %d", size, 0)
+	}
+}
+
 func TestOK_Priority_AccessCount(t *testing.T) {
 	// enable logger
 	EnableLogger(true)
@@ -304,7 +411,7 @@ func TestOK_Priority_Expiration(t *testing.T) {
 	
 	// set
 	c.Set(key1, value1, time.Duration(-1)) //expiration
-	c.Set(key2, value2, time.Duration(1000)) //expiration
+	c.Set(key2, value2, time.Duration(10) * time.Millisecond) //expiration
 	
 	//check data
 	i1, _ := c.GetItem(key1)
@@ -315,7 +422,7 @@ func TestOK_Priority_Expiration(t *testing.T) {
 	if i2.Priority != 1 {
 		t.Errorf("priority(%v) is invalid. expected = %v", i2.Priority, 1)
 	}
-	time.Sleep(1000)
+	time.Sleep(time.Duration(10) * time.Millisecond)
 	if c.Priority(key2) != 0 { //priority will be 0 by expiration 
 		t.Errorf("priority(%v) is invalid. expected = %v", i2.Priority, 1)
 	}
@@ -411,8 +518,8 @@ func TestOK_SetGet_OverWrite(t *testing.T) {
 		}
 	c := New(opt)
 	
-	c.Set(key, value1, time.Duration(10))
-	c.Set(key, value2, time.Duration(10))
+	c.Set(key, value1, time.Duration(10) * time.Second)
+	c.Set(key, value2, time.Duration(10) * time.Second)
 	v, found := c.Get(key)
 	if !found {
 		t.Errorf("key(%v) is not found.", key)
@@ -447,7 +554,7 @@ func TestOK_SetGet_String(t *testing.T) {
 		}
 	c := New(opt)
 	
-	c.Set(key, value, time.Duration(10))
+	c.Set(key, value, time.Duration(10) * time.Second)
 	v, found := c.Get(key)
 	if !found {
 		t.Errorf("key(%v) is not found.", key)
@@ -479,7 +586,7 @@ func TestNG_SetGet_String(t *testing.T) {
 			ThresholdAccessCount: 0,
 		}
 	c := New(opt)
-	c.Set(key, value, time.Duration(10))
+	c.Set(key, value, time.Duration(10) * time.Second)
 	v, found := c.Get(badKey)
 	if !found {
 		t.Logf("key(%v) is not found.", key)
@@ -511,7 +618,7 @@ func TestNG_Set_Chan(t *testing.T) {
 			ThresholdAccessCount: 0,
 		}
 	c := New(opt)
-	err := c.Set(key, value, time.Duration(10))
+	err := c.Set(key, value, time.Duration(10) * time.Second)
 	if (err != nil) {
 		t.Logf("expected error. error = %s", err.Error())
 		return
@@ -531,7 +638,7 @@ func TestNG_Set_Func(t *testing.T) {
 			ThresholdAccessCount: 0,
 		}
 	c := New(opt)
-	err := c.Set(key, value, time.Duration(10))
+	err := c.Set(key, value, time.Duration(10) * time.Second)
 	if (err != nil) {
 		t.Logf("expected error. error = %s", err.Error())
 		return
@@ -552,7 +659,7 @@ func TestOK_Del_String(t *testing.T) {
 		}
 	c := New(opt)
 	
-	c.Set(key, value, time.Duration(10))
+	c.Set(key, value, time.Duration(10) * time.Second)
 	v, found := c.Get(key)
 	if !found {
 		t.Errorf("key(%v) is not found.", key)
@@ -579,3 +686,154 @@ func TestOK_Del_String(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestOK_Get_Expired(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	key := "TestOK_Get_Expired"
+	value := "testString"
+
+	opt := Option{
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := New(opt)
+
+	c.Set(key, value, time.Duration(1000))
+	time.Sleep(1000)
+
+	// expired item is not found, and deleted without optimizer
+	v, found := c.Get(key)
+	if found {
+		t.Errorf("key(%v) is found. value = %v", key, *v)
+		t.FailNow()
+	}
+	if _, found := c.GetItems()[key]; found {
+		t.Errorf("key(%v) is not deleted.", key)
+	}
+	if c.Size() != 0 {
+		t.Errorf("size(%d) is invalid. expected = %d", c.Size(), 0)
+	}
+}
+
+func TestOK_GetItem_Expired(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	key := "TestOK_GetItem_Expired"
+	value := "testString"
+
+	opt := Option{
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := New(opt)
+
+	c.Set(key, value, time.Duration(1000))
+	time.Sleep(1000)
+
+	item, found := c.GetItem(key)
+	if found || item != nil {
+		t.Errorf("key(%v) is found.", key)
+	}
+}
+
+func TestOK_List_Expired(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	key1 := "key1"
+	key2 := "key2"
+	value := "testString"
+
+	opt := Option{
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := New(opt)
+
+	c.Set(key1, value, time.Duration(10) * time.Second)
+	c.Set(key2, value, time.Duration(1000))
+	time.Sleep(1000)
+
+	names := c.List()
+	if len(names) != 1 || names[0] != key1 {
+		t.Errorf("names(%v) is invalid. expected = %v", names, []string{key1})
+	}
+	if len(c.GetItems()) != 1 {
+		t.Errorf("items(%d) is invalid. expected = %d", len(c.GetItems()), 1)
+	}
+}
+
+func TestOK_Typed_SetGet_Struct(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	type Obj struct {
+		V1 string
+		V2 int
+	}
+	key := 1
+	value := Obj{V1: "test1", V2: 1,}
+
+	opt := TypedOption[int, Obj]{
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := NewTyped[int, Obj](opt)
+
+	c.Set(key, value, time.Duration(10) * time.Second)
+	v, found := c.Get(key)
+	if !found {
+		t.Errorf("key(%v) is not found.", key)
+		t.FailNow()
+	}
+	if v != value {
+		t.Errorf("v(%v) is not same value with value(%v).", v, value)
+	}
+	item, _ := c.GetItem(key)
+	if item.AccessCount != 1 {
+		t.Errorf("access count(%d) is invalid. expected = %d", item.AccessCount, 1)
+	}
+}
+
+func TestNG_Typed_Get_NotFound(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, []byte]{
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := NewTyped[string, []byte](opt)
+
+	c.Set("key", []byte("value"), time.Duration(10) * time.Second)
+	v, found := c.Get("badKey")
+	if found {
+		t.Errorf("key(%v) is found.", "badKey")
+	}
+	if v != nil {
+		t.Errorf("v(%v) is not zero value.", v)
+	}
+}
+
+func TestOK_Typed_Optimize(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			ThresholdSize: 1,
+			ThresholdAccess: 0,
+			ThresholdAccessCount: 0,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(20) * time.Second)
+	c.Optimize()
+
+	if len(c.List()) != 0 {
+		t.Errorf("items(%v) are not deleted by compaction.", c.List())
+	}
+}
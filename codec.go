@@ -0,0 +1,309 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec codec converts value of item to bytes.
+type Codec interface {
+	// Marshal encode value.
+	// param v - value
+	// return arg1 - encoded bytes
+	// return arg2 - Error
+	Marshal(v interface{}) ([]byte, error)
+
+	// Unmarshal decode bytes to value.
+	// param data - encoded bytes
+	// param v - pointer to value
+	// return arg1 - Error
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// GobCodec codec by encoding/gob (default)
+type GobCodec struct{}
+
+// Marshal encode value by gob.
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Unmarshal decode value by gob.
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// JSONCodec codec by encoding/json
+type JSONCodec struct{}
+
+// Marshal encode value by json.
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decode value by json.
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// RawCodec codec which passes []byte through.
+// Value must be []byte.
+type RawCodec struct{}
+
+// Marshal return value as it is.
+func (RawCodec) Marshal(v interface{}) ([]byte, error) {
+	b, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("value is not []byte. type = %T", v)
+	}
+	return b, nil
+}
+
+// Unmarshal copy bytes to *[]byte or *interface{}.
+func (RawCodec) Unmarshal(data []byte, v interface{}) error {
+	b := append([]byte{}, data...)
+	switch p := v.(type) {
+	case *[]byte:
+		*p = b
+	case *interface{}:
+		*p = b
+	default:
+		return fmt.Errorf("value is not *[]byte. type = %T", v)
+	}
+	return nil
+}
+
+// Registry registry of concrete types of values.
+// Value of interface type is encoded with the name of its concrete type,
+// so that it is decoded to the original type.
+type Registry struct {
+	mu sync.RWMutex
+	types map[string]reflect.Type
+	names map[reflect.Type]string
+}
+
+// DefaultRegistry registry used when Registry is not set in option
+var DefaultRegistry = NewRegistry()
+
+// NewRegistry create registry, in which builtin types are registered.
+// return arg1 - instance of Registry
+func NewRegistry() *Registry {
+	r := &Registry{types: map[string]reflect.Type{}, names: map[reflect.Type]string{}}
+	for _, v := range []interface{}{
+		false, 0, int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), float32(0), float64(0), "",
+		[]byte{}, []string{}, []int{}, []interface{}{}, map[string]string{}, map[string]interface{}{},
+	} {
+		r.Register(reflect.TypeOf(v).String(), v)
+	}
+	return r
+}
+
+// RegisterType register concrete type of value to DefaultRegistry.
+// param name - name of type
+// param value - value of type
+func RegisterType(name string, value interface{}) {
+	DefaultRegistry.Register(name, value)
+}
+
+// Register register concrete type of value.
+// It panics if name or type is registered for other type or name, as gob.Register does.
+// param name - name of type
+// param value - value of type
+func (r *Registry) Register(name string, value interface{}) {
+	t := reflect.TypeOf(value)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if registered, found := r.types[name]; found && registered != t {
+		panic(fmt.Sprintf("cache: registering duplicate types for %q: %s != %s", name, registered, t))
+	}
+	if registered, found := r.names[t]; found && registered != name {
+		panic(fmt.Sprintf("cache: registering duplicate names for %s: %q != %q", t, registered, name))
+	}
+	r.types[name] = t
+	r.names[t] = name
+}
+
+// Codec wrap codec to encode the name of concrete type with value.
+// Wrapped codec decodes value to *interface{} in its original type.
+// param codec - codec of value
+// return arg1 - wrapped codec
+func (r *Registry) Codec(codec Codec) Codec {
+	return &registryCodec{registry: r, codec: codec}
+}
+
+type registryCodec struct {
+	registry *Registry
+	codec Codec
+}
+
+// Marshal encode length of name, name and value.
+func (c *registryCodec) Marshal(v interface{}) ([]byte, error) {
+	c.registry.mu.RLock()
+	name, found := c.registry.names[reflect.TypeOf(v)]
+	c.registry.mu.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("type of value is not registered. type = %T", v)
+	}
+	data, err := c.codec.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	b := binary.AppendUvarint(nil, uint64(len(name)))
+	b = append(b, name...)
+	return append(b, data...), nil
+}
+
+// Unmarshal decode value in the registered type of the name.
+func (c *registryCodec) Unmarshal(data []byte, v interface{}) error {
+	n, size := binary.Uvarint(data)
+	if size <= 0 || uint64(len(data) - size) < n {
+		return errors.New("name of type is broken")
+	}
+	name := string(data[size : size + int(n)])
+	data = data[size + int(n):]
+
+	p, ok := v.(*interface{})
+	if !ok {
+		return c.codec.Unmarshal(data, v)
+	}
+	c.registry.mu.RLock()
+	t, found := c.registry.types[name]
+	c.registry.mu.RUnlock()
+	if !found {
+		return fmt.Errorf("type is not registered. name = %s", name)
+	}
+	value := reflect.New(t)
+	if err := c.codec.Unmarshal(data, value.Interface()); err != nil {
+		return err
+	}
+	*p = value.Elem().Interface()
+	return nil
+}
+
+// newValueCodec create codec of value from option.
+// If V is interface type, codec is wrapped by Registry.
+// param opt - option
+// return arg1 - codec of value
+func newValueCodec[K comparable, V any](opt *TypedOption[K, V]) Codec {
+	codec := opt.Codec
+	if codec == nil {
+		codec = GobCodec{}
+	}
+	if reflect.TypeFor[V]().Kind() != reflect.Interface {
+		return codec
+	}
+	registry := opt.Registry
+	if registry == nil {
+		registry = DefaultRegistry
+	}
+	return registry.Codec(codec)
+}
+
+// marshal encode value by codec of value.
+// param value - value of item
+// return arg1 - encoded bytes
+// return arg2 - Error
+func (c *cache[K, V]) marshal(value V) ([]byte, error) {
+	return c.codec.Marshal(value)
+}
+
+// unmarshal decode value by codec of value.
+// param data - encoded bytes
+// return arg1 - value of item
+// return arg2 - Error
+func (c *cache[K, V]) unmarshal(data []byte) (value V, err error) {
+	err = c.codec.Unmarshal(data, &value)
+	return value, err
+}
+
+// encode encode value of item to bytes stored in memory, if Compressor or KeyProvider is set in option.
+// With KeyProvider, every value is encrypted, and value which can not be encoded is error.
+// Without KeyProvider, value which is not compressed is kept as it is.
+// param item - item whose size is not set
+// return arg1 - Error
+func (c *cache[K, V]) encode(item *TypedItem[K, V]) error {
+	keys := c.option.KeyProvider
+	if c.option.Compressor == nil && keys == nil {
+		return nil
+	}
+	data, err := c.marshal(item.Object)
+	if err != nil {
+		if keys != nil {
+			return err
+		}
+		Debug("value is not compressed. key = %v error = %v", item.Key, err)
+		return nil
+	}
+	data, compressed := c.compress(item.Key, data)
+	if keys == nil && !compressed {
+		return nil
+	}
+	if keys != nil {
+		if data, err = encrypt(keys, data); err != nil {
+			return err
+		}
+	}
+	var zero V
+	item.Object = zero
+	item.data.Store(&data)
+	item.compressed, item.encrypted = compressed, keys != nil
+	return nil
+}
+
+// Value get value of item got by GetItems, which is decoded if it is compressed or encrypted.
+// Value which can not be decoded is returned as zero value.
+// param item - item of cache
+// return arg1 - value of item
+func (c *cache[K, V]) Value(item *TypedItem[K, V]) V {
+	return c.value(item)
+}
+
+// value get value of item, which is decoded if it is stored in bytes.
+// Value which can not be decoded is returned as zero value.
+// param item - item
+// return arg1 - value of item
+func (c *cache[K, V]) value(item *TypedItem[K, V]) V {
+	if item.encoded() == nil {
+		return item.Object
+	}
+	data, err := c.encodedValue(item)
+	if err == nil {
+		var value V
+		if value, err = c.unmarshal(data); err == nil {
+			return value
+		}
+	}
+	Warn("decode error key = %v error = %v", item.Key, err)
+	var zero V
+	return zero
+}
+
+// encodedValue get value of item encoded by codec, which is decrypted and decompressed.
+// param item - item
+// return arg1 - encoded value
+// return arg2 - Error
+func (c *cache[K, V]) encodedValue(item *TypedItem[K, V]) (data []byte, err error) {
+	if data = item.encoded(); data == nil {
+		return c.marshal(item.Object)
+	}
+	if item.encrypted {
+		if data, err = decrypt(c.option.KeyProvider, data); err != nil {
+			return nil, err
+		}
+	}
+	if item.compressed {
+		return c.option.Compressor.Decompress(data)
+	}
+	return data, nil
+}
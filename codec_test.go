@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type codecValue struct {
+	Name string
+	Tags []string
+}
+
+func init() {
+	RegisterType("codecValue", codecValue{})
+}
+
+func TestOK_Codec(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	value := codecValue{Name: "tico8", Tags: []string{"a", "b"}}
+	for _, codec := range []Codec{GobCodec{}, JSONCodec{}} {
+		data, err := codec.Marshal(value)
+		if err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		var decoded codecValue
+		if err := codec.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		if !reflect.DeepEqual(decoded, value) {
+			t.Errorf("decoded(%v) by %T is invalid.", decoded, codec)
+		}
+	}
+
+	data, _ := RawCodec{}.Marshal([]byte("value"))
+	var decoded []byte
+	if err := (RawCodec{}).Unmarshal(data, &decoded); err != nil || string(decoded) != "value" {
+		t.Errorf("decoded(%s) is invalid. error = %v", decoded, err)
+	}
+}
+
+func TestOK_Codec_Registry(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	for _, codec := range []Codec{GobCodec{}, JSONCodec{}} {
+		c := New(Option{Codec: codec})
+		c.Set("key1", codecValue{Name: "tico8"}, time.Duration(10) * time.Second)
+		c.Set("key2", 2, time.Duration(10) * time.Second)
+		c.Set("key3", map[string]interface{}{"name": "tico8"}, time.Duration(10) * time.Second)
+
+		var buf bytes.Buffer
+		if err := c.Save(&buf); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		r := New(Option{Codec: codec})
+		if err := r.Load(&buf); err != nil {
+			t.Fatalf("error = %v", err)
+		}
+		// decoded in original type, even by JSONCodec
+		if v, _ := r.Get("key1"); !reflect.DeepEqual(*v, codecValue{Name: "tico8"}) {
+			t.Errorf("v(%#v) by %T is invalid.", *v, codec)
+		}
+		if v, _ := r.Get("key2"); *v != 2 {
+			t.Errorf("v(%#v) by %T is invalid.", *v, codec)
+		}
+		if v, _ := r.Get("key3"); !reflect.DeepEqual(*v, map[string]interface{}{"name": "tico8"}) {
+			t.Errorf("v(%#v) by %T is invalid.", *v, codec)
+		}
+	}
+}
+
+func TestOK_Codec_AOF(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewTyped[string, []byte](TypedOption[string, []byte]{Codec: RawCodec{}})
+	c.OpenAOF(path)
+	c.Set("key1", []byte("value"), time.Duration(10) * time.Second)
+	c.Set("key2", []byte("value"), time.Duration(10) * time.Second)
+	c.Del("key2")
+	c.Close()
+
+	r := NewTyped[string, []byte](TypedOption[string, []byte]{Codec: RawCodec{}})
+	if err := r.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer r.Close()
+	if v, _ := r.Get("key1"); string(v) != "value" {
+		t.Errorf("v(%s) is invalid.", v)
+	}
+	if _, found := r.Get("key2"); found {
+		t.Errorf("key2 is replayed.")
+	}
+}
+
+func TestNG_Codec(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	if _, err := (RawCodec{}).Marshal("value"); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	var s string
+	if err := (RawCodec{}).Unmarshal([]byte("value"), &s); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// unregistered type
+	type unregistered struct {
+		Name string
+	}
+	c := New(Option{})
+	c.Set("key", unregistered{Name: "tico8"}, time.Duration(10) * time.Second)
+	if err := c.Save(&bytes.Buffer{}); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// type is registered to other registry
+	registry := NewRegistry()
+	registry.Register("unregistered", unregistered{})
+	c = New(Option{Registry: registry})
+	c.Set("key", unregistered{Name: "tico8"}, time.Duration(10) * time.Second)
+	var buf bytes.Buffer
+	c.Save(&buf)
+	if err := New(Option{}).Load(&buf); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// broken name of type
+	if err := DefaultRegistry.Codec(GobCodec{}).Unmarshal([]byte{100, 1}, new(interface{})); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// duplicate registration
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("panic is not occurred.")
+			}
+		}()
+		registry.Register("unregistered", codecValue{})
+	}()
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+)
+
+const (
+	// DefaultCompressMinSize minimum size of encoded value to be compressed
+	DefaultCompressMinSize = 1024
+)
+
+// Compressor compressor of encoded value of item.
+type Compressor interface {
+	// Compress compress data.
+	// param data - encoded value
+	// return arg1 - compressed data
+	// return arg2 - Error
+	Compress(data []byte) ([]byte, error)
+
+	// Decompress decompress data.
+	// param data - compressed data
+	// return arg1 - encoded value
+	// return arg2 - Error
+	Decompress(data []byte) ([]byte, error)
+}
+
+// GzipCompressor compressor by compress/gzip
+type GzipCompressor struct {
+	Level int // default is gzip.DefaultCompression
+}
+
+// Compress compress data by gzip.
+func (c GzipCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := gzip.NewWriterLevel(&buf, compressLevel(c.Level))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompress data by gzip.
+func (GzipCompressor) Decompress(data []byte) ([]byte, error) {
+	r, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// FlateCompressor compressor by compress/flate, which has less overhead than gzip
+type FlateCompressor struct {
+	Level int // default is flate.DefaultCompression
+}
+
+// Compress compress data by flate.
+func (c FlateCompressor) Compress(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, compressLevel(c.Level))
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decompress decompress data by flate.
+func (FlateCompressor) Decompress(data []byte) ([]byte, error) {
+	r := flate.NewReader(bytes.NewReader(data))
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// compressLevel get level of compression, 0 is regarded as default.
+func compressLevel(level int) int {
+	if level == 0 {
+		return flate.DefaultCompression
+	}
+	return level
+}
+
+// compress compress encoded value, if Compressor is set in option.
+// Value whose encoded size is less than CompressMinSize, or which is not compressed smaller, is not compressed.
+// param key - key of item
+// param data - encoded value
+// return arg1 - compressed value, or data as it is
+// return arg2 - If data is compressed, return true.
+func (c *cache[K, V]) compress(key K, data []byte) ([]byte, bool) {
+	compressor := c.option.Compressor
+	if compressor == nil {
+		return data, false
+	}
+	minSize := c.option.CompressMinSize
+	if minSize <= 0 {
+		minSize = DefaultCompressMinSize
+	}
+	if len(data) < minSize {
+		return data, false
+	}
+	compressed, err := compressor.Compress(data)
+	if err != nil {
+		Warn("compress error key = %v error = %v", key, err)
+		return data, false
+	}
+	if len(compressed) >= len(data) {
+		return data, false
+	}
+	return compressed, true
+}
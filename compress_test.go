@@ -0,0 +1,149 @@
+package cache
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type failCompressor struct{}
+
+func (failCompressor) Compress(data []byte) ([]byte, error) {
+	return nil, errors.New("compress error")
+}
+
+func (failCompressor) Decompress(data []byte) ([]byte, error) {
+	return nil, errors.New("decompress error")
+}
+
+func TestOK_Compress(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	value := strings.Repeat(`{"name":"tico8","tags":["a","b"]}`, 100)
+	for _, compressor := range []Compressor{GzipCompressor{}, FlateCompressor{Level: 9}} {
+		c := NewTyped[string, string](TypedOption[string, string]{Compressor: compressor})
+		plain := NewTyped[string, string](TypedOption[string, string]{})
+		c.Set("key", value, time.Duration(10) * time.Second)
+		plain.Set("key", value, time.Duration(10) * time.Second)
+
+		if v, found := c.Get("key"); !found || v != value {
+			t.Errorf("v(%d) by %T is invalid.", len(v), compressor)
+		}
+		item, _ := c.GetItem("key")
+		if !item.compressed || item.Object != "" {
+			t.Errorf("value is not compressed by %T.", compressor)
+		}
+		// size of item is compressed size
+		if c.Size() * 10 > plain.Size() || c.Size() != c.SizeOfItem(item) {
+			t.Errorf("size(%d) by %T is invalid. plain = %d", c.Size(), compressor, plain.Size())
+		}
+	}
+}
+
+func TestOK_Compress_MinSize(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, string](TypedOption[string, string]{Compressor: GzipCompressor{}, CompressMinSize: 100})
+	c.Set("small", strings.Repeat("a", 50), time.Duration(10) * time.Second)
+	c.Set("large", strings.Repeat("a", 200), time.Duration(10) * time.Second)
+	if item, _ := c.GetItem("small"); item.compressed {
+		t.Errorf("small value is compressed.")
+	}
+	if item, _ := c.GetItem("large"); !item.compressed {
+		t.Errorf("large value is not compressed.")
+	}
+}
+
+func TestOK_Compress_Value(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, string](TypedOption[string, string]{Compressor: GzipCompressor{}, CompressMinSize: 100})
+	values := map[string]string{"small": strings.Repeat("a", 50), "large": strings.Repeat("a", 200)}
+	for key, value := range values {
+		c.Set(key, value, time.Duration(10) * time.Second)
+	}
+	items := c.GetItems()
+	if items["large"].Object != "" {
+		t.Errorf("Object of compressed item is not empty.")
+	}
+	for key, value := range values {
+		if v := c.Value(items[key]); v != value {
+			t.Errorf("v(%d) of key(%v) is invalid.", len(v), key)
+		}
+	}
+}
+
+func TestOK_Compress_Untyped(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	value := map[string]interface{}{"name": strings.Repeat("tico8", 500)}
+	c := New(Option{Compressor: FlateCompressor{}, Codec: JSONCodec{}})
+	evicted := make(chan interface{}, 1)
+	c.OnEvict(func(key string, value interface{}, reason EvictionReason) {
+		evicted <- value
+	})
+	ch := c.Watch("")
+	c.Set("key", value, time.Duration(10) * time.Second)
+
+	if v, _ := c.Get("key"); !reflect.DeepEqual(*v, value) {
+		t.Errorf("v(%v) is invalid.", *v)
+	}
+	if event := <-ch; !reflect.DeepEqual(event.NewValue, value) {
+		t.Errorf("event(%v) is invalid.", event)
+	}
+
+	// snapshot has decompressed value
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	r := New(Option{Codec: JSONCodec{}})
+	if err := r.Load(&buf); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if v, _ := r.Get("key"); !reflect.DeepEqual(*v, value) {
+		t.Errorf("v(%v) is invalid.", *v)
+	}
+
+	c.Del("key")
+	if v := <-evicted; !reflect.DeepEqual(v, value) {
+		t.Errorf("evicted(%v) is invalid.", v)
+	}
+}
+
+func TestNG_Compress(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// value which can not be compressed is kept as it is
+	c := NewTyped[string, string](TypedOption[string, string]{Compressor: failCompressor{}, CompressMinSize: 1})
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	if item, _ := c.GetItem("key"); item.compressed || item.Object != "value" {
+		t.Errorf("item(%v) is invalid.", item)
+	}
+
+	// value which can not be encoded is kept as it is
+	type unregistered struct {
+		Name string
+	}
+	u := New(Option{Compressor: GzipCompressor{}, CompressMinSize: 1})
+	u.Set("key", unregistered{Name: "tico8"}, time.Duration(10) * time.Second)
+	if v, _ := u.Get("key"); (*v).(unregistered).Name != "tico8" {
+		t.Errorf("v(%v) is invalid.", *v)
+	}
+
+	// invalid level
+	if _, err := (GzipCompressor{Level: 100}).Compress([]byte("value")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, err := (GzipCompressor{}).Decompress([]byte("broken")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+const (
+	keyIDSize = 4 // id of key at the head of encrypted data
+)
+
+// KeyProvider provider of keys of AES-GCM.
+// Data is encrypted by current key, and decrypted by the key of id recorded in data,
+// so that data encrypted by old keys can be decrypted after rotation.
+type KeyProvider interface {
+	// CurrentKey get key to encrypt.
+	// return arg1 - id of key
+	// return arg2 - key of 16, 24 or 32 bytes
+	// return arg3 - Error
+	CurrentKey() (uint32, []byte, error)
+
+	// Key get key to decrypt.
+	// param id - id of key
+	// return arg1 - key of 16, 24 or 32 bytes
+	// return arg2 - Error
+	Key(id uint32) ([]byte, error)
+}
+
+// KeyRing KeyProvider which holds keys in memory.
+type KeyRing struct {
+	mu sync.RWMutex
+	current uint32
+	keys map[uint32][]byte
+}
+
+// NewKeyRing create key ring with current key.
+// param id - id of key
+// param key - key of 16, 24 or 32 bytes
+// return arg1 - instance of KeyRing
+// return arg2 - Error
+func NewKeyRing(id uint32, key []byte) (*KeyRing, error) {
+	r := &KeyRing{keys: map[uint32][]byte{}}
+	if err := r.Rotate(id, key); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Add add key to decrypt data encrypted by it.
+// param id - id of key
+// param key - key of 16, 24 or 32 bytes
+// return arg1 - Error
+func (r *KeyRing) Add(id uint32, key []byte) error {
+	if _, err := aes.NewCipher(key); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if registered, found := r.keys[id]; found && string(registered) != string(key) {
+		return fmt.Errorf("other key is added for id. id = %d", id)
+	}
+	r.keys[id] = append([]byte{}, key...)
+	return nil
+}
+
+// Rotate add key, and make it current key.
+// Old keys are kept to decrypt data encrypted by them.
+// param id - id of key
+// param key - key of 16, 24 or 32 bytes
+// return arg1 - Error
+func (r *KeyRing) Rotate(id uint32, key []byte) error {
+	if err := r.Add(id, key); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	r.current = id
+	r.mu.Unlock()
+	return nil
+}
+
+// Remove remove old key, after data encrypted by it is encrypted by current key.
+// param id - id of key
+// return arg1 - Error
+func (r *KeyRing) Remove(id uint32) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if id == r.current {
+		return errors.New("current key can not be removed")
+	}
+	delete(r.keys, id)
+	return nil
+}
+
+// CurrentKey get current key.
+func (r *KeyRing) CurrentKey() (uint32, []byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.current, r.keys[r.current], nil
+}
+
+// Key get key of id.
+func (r *KeyRing) Key(id uint32) ([]byte, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	key, found := r.keys[id]
+	if !found {
+		return nil, fmt.Errorf("key is not found. id = %d", id)
+	}
+	return key, nil
+}
+
+// RotateKeys encrypt items encrypted by old keys with current key of KeyProvider.
+// Data of items is swapped in place, so that order of eviction, events and stats are not changed.
+// If append-only file is opened, it is rewritten with current key.
+// Snapshot files saved before should be saved again before old keys are removed.
+// return arg1 - Error
+func (c *cache[K, V]) RotateKeys() error {
+	keys := c.option.KeyProvider
+	if keys == nil {
+		return errors.New("KeyProvider is not set")
+	}
+	current, _, err := keys.CurrentKey()
+	if err != nil {
+		return err
+	}
+	rotated := 0
+	for _, item := range c.snapshot() {
+		old := item.data.Load()
+		if !item.encrypted || old == nil || encryptedKeyID(*old) == current {
+			continue
+		}
+		data, err := decrypt(keys, *old)
+		if err == nil {
+			data, err = encrypt(keys, data)
+		}
+		if err != nil {
+			return fmt.Errorf("rotate key = %v: %w", item.Key, err)
+		}
+		// size of item is not changed, because encrypted data has the same length with any key
+		if item.data.CompareAndSwap(old, &data) {
+			rotated++
+		}
+	}
+	Debug("rotated keys of items. count = %d", rotated)
+	if c.aof.Load() != nil {
+		return c.RewriteAOF()
+	}
+	return nil
+}
+
+// encrypt encrypt data by current key.
+// Encrypted data is id of key, nonce and sealed data.
+// param keys - provider of keys
+// param data - data
+// return arg1 - encrypted data
+// return arg2 - Error
+func encrypt(keys KeyProvider, data []byte) ([]byte, error) {
+	id, key, err := keys.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, keyIDSize + aead.NonceSize(), keyIDSize + aead.NonceSize() + len(data) + aead.Overhead())
+	binary.BigEndian.PutUint32(b, id)
+	nonce := b[keyIDSize:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(b, nonce, data, nil), nil
+}
+
+// decrypt decrypt data by the key of id in data.
+// param keys - provider of keys
+// param data - encrypted data
+// return arg1 - data
+// return arg2 - Error
+func decrypt(keys KeyProvider, data []byte) ([]byte, error) {
+	if keys == nil {
+		return nil, errors.New("data is encrypted, but KeyProvider is not set")
+	}
+	if len(data) < keyIDSize {
+		return nil, errors.New("encrypted data is broken")
+	}
+	key, err := keys.Key(encryptedKeyID(data))
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < keyIDSize + aead.NonceSize() {
+		return nil, errors.New("encrypted data is broken")
+	}
+	nonce := data[keyIDSize : keyIDSize + aead.NonceSize()]
+	return aead.Open(nil, nonce, data[keyIDSize + aead.NonceSize():], nil)
+}
+
+// encryptedKeyID get id of key which encrypted data.
+func encryptedKeyID(data []byte) uint32 {
+	return binary.BigEndian.Uint32(data)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 16)
+)
+
+func TestOK_Encrypt(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	keys, _ := NewKeyRing(1, key1)
+	c := NewTyped[string, string](TypedOption[string, string]{KeyProvider: keys})
+	c.Set("key", "secret", time.Duration(10) * time.Second)
+	if v, found := c.Get("key"); !found || v != "secret" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	item, _ := c.GetItem("key")
+	if !item.encrypted || item.Object != "" || bytes.Contains(item.encoded(), []byte("secret")) {
+		t.Errorf("value is not encrypted.")
+	}
+	if c.Size() != c.SizeOfItem(item) || len(item.encoded()) <= len("secret") {
+		t.Errorf("size(%d) is invalid.", c.Size())
+	}
+
+	// compressed and encrypted
+	z := NewTyped[string, string](TypedOption[string, string]{KeyProvider: keys, Compressor: GzipCompressor{}})
+	value := strings.Repeat("secret", 1000)
+	z.Set("key", value, time.Duration(10) * time.Second)
+	if v, _ := z.Get("key"); v != value {
+		t.Errorf("v(%d) is invalid.", len(v))
+	}
+	if item, _ := z.GetItem("key"); !item.compressed || !item.encrypted || len(item.encoded()) > len(value) / 10 {
+		t.Errorf("item(%d) is invalid.", len(item.encoded()))
+	}
+}
+
+func TestOK_Encrypt_Snapshot(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	keys, _ := NewKeyRing(1, key1)
+	c := New(Option{KeyProvider: keys})
+	c.Set("user:tico8", "secret", time.Duration(10) * time.Second)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if bytes.Contains(buf.Bytes(), []byte("secret")) || bytes.Contains(buf.Bytes(), []byte("tico8")) {
+		t.Errorf("snapshot is not encrypted.")
+	}
+	data := buf.Bytes()
+
+	r := New(Option{KeyProvider: keys})
+	if err := r.Load(bytes.NewReader(data)); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if v, _ := r.Get("user:tico8"); *v != "secret" {
+		t.Errorf("v(%v) is invalid.", *v)
+	}
+
+	// no key
+	if err := New(Option{}).Load(bytes.NewReader(data)); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	other, _ := NewKeyRing(2, key2)
+	if err := New(Option{KeyProvider: other}).Load(bytes.NewReader(data)); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
+
+func TestOK_Encrypt_AOF(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	keys, _ := NewKeyRing(1, key1)
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := New(Option{KeyProvider: keys})
+	c.OpenAOF(path)
+	c.Set("user:tico8", "secret", time.Duration(10) * time.Second)
+	c.Set("user:other", "secret", time.Duration(10) * time.Second)
+	c.Del("user:other")
+	c.Close()
+	if b, _ := os.ReadFile(path); bytes.Contains(b, []byte("secret")) || bytes.Contains(b, []byte("tico8")) {
+		t.Errorf("append-only file is not encrypted.")
+	}
+
+	r := New(Option{KeyProvider: keys})
+	if err := r.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	r.Close()
+	if v, _ := r.Get("user:tico8"); *v != "secret" {
+		t.Errorf("v(%v) is invalid.", *v)
+	}
+	if _, found := r.Get("user:other"); found {
+		t.Errorf("user:other is replayed.")
+	}
+
+	// no key
+	if err := New(Option{}).OpenAOF(path); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
+
+func TestOK_RotateKeys(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	keys, _ := NewKeyRing(1, key1)
+	path := filepath.Join(t.TempDir(), "cache.aof")
+	c := NewTyped[string, string](TypedOption[string, string]{KeyProvider: keys, Shards: 4})
+	c.OpenAOF(path)
+	defer c.Close()
+	c.Set("key1", "secret1", time.Duration(10) * time.Second)
+	c.Set("key2", "secret2", time.Duration(10) * time.Second)
+
+	if err := keys.Rotate(2, key2); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	c.Set("key3", "secret3", time.Duration(10) * time.Second)
+	if item, _ := c.GetItem("key3"); encryptedKeyID(item.encoded()) != 2 {
+		t.Errorf("key3 is not encrypted by current key.")
+	}
+	if err := c.RotateKeys(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if err := keys.Remove(1); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	for key, value := range map[string]string{"key1": "secret1", "key2": "secret2", "key3": "secret3"} {
+		if v, _ := c.Get(key); v != value {
+			t.Errorf("v(%v) of %v is invalid.", v, key)
+		}
+		if item, _ := c.GetItem(key); encryptedKeyID(item.encoded()) != 2 {
+			t.Errorf("%v is not encrypted by current key.", key)
+		}
+	}
+
+	// append-only file is rewritten by current key
+	current, _ := NewKeyRing(2, key2)
+	r := NewTyped[string, string](TypedOption[string, string]{KeyProvider: current})
+	if err := r.OpenAOF(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	defer r.Close()
+	if len(r.List()) != 3 {
+		t.Errorf("keys(%v) are invalid.", r.List())
+	}
+}
+
+func TestOK_RotateKeys_Eviction(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	keys, _ := NewKeyRing(1, key1)
+	probe := New(Option{KeyProvider: keys})
+	probe.Set("key1", "secret1", time.Duration(10) * time.Second)
+	itemSize := probe.Size()
+
+	// 2 items are held
+	r := newEvictRecorder()
+	c := New(Option{KeyProvider: keys, Eviction: EvictLRU, ThresholdSize: itemSize * 5 / 2})
+	c.OnEvict(r.record)
+	c.Set("key1", "secret1", time.Duration(10) * time.Second)
+	c.Set("key2", "secret2", time.Duration(10) * time.Second)
+	c.Get("key1")
+
+	keys.Rotate(2, key2)
+	if err := c.RotateKeys(); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if _, found := r.reason("key1"); found {
+		t.Errorf("OnEvict is called by rotation.")
+	}
+	if stats := c.Stats(); stats.Evictions[ReasonReplaced] != 0 {
+		t.Errorf("evictions(%v) are invalid.", stats.Evictions)
+	}
+	if c.Size() != itemSize * 2 {
+		t.Errorf("size(%d) is invalid.", c.Size())
+	}
+
+	// order of eviction is kept, key2 is least recently used
+	c.Set("key3", "secret3", time.Duration(10) * time.Second)
+	if reason, _ := r.reason("key2"); reason != ReasonCapacity {
+		t.Errorf("reason(%v) is invalid.", reason)
+	}
+	if v, found := c.Get("key1"); !found || *v != "secret1" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+}
+
+func TestNG_Encrypt(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	if _, err := NewKeyRing(1, []byte("short")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	keys, _ := NewKeyRing(1, key1)
+	if err := keys.Add(1, key2); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if err := keys.Remove(1); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, err := keys.Key(2); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// value which can not be encoded is not set in plain
+	type unregistered struct {
+		Name string
+	}
+	c := New(Option{KeyProvider: keys})
+	if err := c.Set("key", unregistered{Name: "tico8"}, time.Duration(10) * time.Second); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.Get("key"); found {
+		t.Errorf("key is set.")
+	}
+
+	// tampered data
+	data, _ := encrypt(keys, []byte("secret"))
+	data[len(data) - 1] ^= 1
+	if _, err := decrypt(keys, data); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, err := decrypt(keys, data[:keyIDSize + 1]); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	if err := New(Option{}).RotateKeys(); err == nil {
+		t.Errorf("error is not returned.")
+	}
+}
@@ -0,0 +1,61 @@
+package cache
+
+// EvictionReason reason why item is removed from cache
+type EvictionReason int
+
+const (
+	// ReasonExpired item is expired
+	ReasonExpired EvictionReason = iota
+	// ReasonCapacity item is evicted because the size of cache is greater than ThresholdSize
+	ReasonCapacity
+	// ReasonLowPriority item is dropped by eviction policy on Optimize
+	ReasonLowPriority
+	// ReasonDeleted item is deleted by Del
+	ReasonDeleted
+	// ReasonReplaced item is replaced by Set or reloading
+	ReasonReplaced
+)
+
+var reasonNames = [...]string{"expired", "capacity", "low_priority", "deleted", "replaced"}
+
+// EvictionReasons all reasons of eviction
+var EvictionReasons = []EvictionReason{ReasonExpired, ReasonCapacity, ReasonLowPriority, ReasonDeleted, ReasonReplaced}
+
+// String get name of reason.
+func (r EvictionReason) String() string {
+	if r < 0 || int(r) >= len(reasonNames) {
+		return "unknown"
+	}
+	return reasonNames[r]
+}
+
+// eviction item removed from cache, and its reason.
+type eviction[K comparable, V any] struct {
+	item *TypedItem[K, V]
+	reason EvictionReason
+}
+
+// OnEvict set callback called when item is removed from cache.
+// Callback is called outside the lock of cache, so it may call methods of cache.
+// param fn - callback with key, value and reason of removed item, nil to unset
+func (c *cache[K, V]) OnEvict(fn func(key K, value V, reason EvictionReason)) {
+	if fn == nil {
+		c.onEvict.Store(nil)
+		return
+	}
+	c.onEvict.Store(&fn)
+}
+
+// notify call callback and send events for removed items.
+// param evicted - removed items
+func (c *cache[K, V]) notify(evicted []eviction[K, V]) {
+	for _, e := range evicted {
+		c.stats.evictions[e.reason].Add(1)
+	}
+	if fn := c.onEvict.Load(); fn != nil {
+		for _, e := range evicted {
+			(*fn)(e.item.Key, c.value(e.item), e.reason)
+		}
+	}
+	c.emitEvicted(evicted)
+}
@@ -0,0 +1,164 @@
+package cache
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// evictRecorder record callbacks of OnEvict.
+type evictRecorder struct {
+	mu sync.Mutex
+	reasons map[string]EvictionReason
+	values map[string]interface{}
+}
+
+func newEvictRecorder() *evictRecorder {
+	return &evictRecorder{reasons: map[string]EvictionReason{}, values: map[string]interface{}{}}
+}
+
+func (r *evictRecorder) record(key string, value interface{}, reason EvictionReason) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons[key] = reason
+	r.values[key] = value
+}
+
+func (r *evictRecorder) reason(key string) (EvictionReason, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	reason, found := r.reasons[key]
+	return reason, found
+}
+
+// dropPolicy drop all items on Optimize.
+type dropPolicy struct {
+	fifoPolicy
+}
+
+func (p *dropPolicy) Compact(items []*Item) (drop, order []*Item) {
+	return items, nil
+}
+
+func TestOK_OnEvict_Deleted(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	r := newEvictRecorder()
+	c := New(Option{})
+	c.OnEvict(r.record)
+
+	c.Set("key1", "value1", time.Duration(10) * time.Second)
+	c.Set("key1", "value2", time.Duration(10) * time.Second)
+	if reason, _ := r.reason("key1"); reason != ReasonReplaced || r.values["key1"] != "value1" {
+		t.Errorf("reason(%v) or value(%v) is invalid.", reason, r.values["key1"])
+	}
+
+	c.Del("key1")
+	if reason, _ := r.reason("key1"); reason != ReasonDeleted || r.values["key1"] != "value2" {
+		t.Errorf("reason(%v) or value(%v) is invalid.", reason, r.values["key1"])
+	}
+
+	// not found
+	c.Del("key2")
+	if _, found := r.reason("key2"); found {
+		t.Errorf("key2 is notified.")
+	}
+}
+
+func TestOK_OnEvict_Expired(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	r := newEvictRecorder()
+	c := New(Option{})
+	c.OnEvict(r.record)
+
+	c.Set("key1", "value", time.Duration(10) * time.Millisecond)
+	c.Set("key2", "value", time.Duration(10) * time.Millisecond)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+
+	c.Get("key1")
+	c.Optimize()
+	for _, key := range []string{"key1", "key2"} {
+		if reason, found := r.reason(key); !found || reason != ReasonExpired {
+			t.Errorf("reason(%v) of key(%v) is invalid.", reason, key)
+		}
+	}
+}
+
+func TestOK_OnEvict_Capacity(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	r := newEvictRecorder()
+	c := New(Option{Eviction: EvictLRU})
+	c.OnEvict(r.record)
+
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size()
+	c.Set("key2", "value", time.Duration(10) * time.Second)
+	if reason, found := r.reason("key1"); !found || reason != ReasonCapacity {
+		t.Errorf("reason(%v) is invalid.", reason)
+	}
+
+	// compaction by Optimize
+	p := New(Option{})
+	p.OnEvict(r.record)
+	p.Set("key3", "value", time.Duration(10) * time.Second)
+	p.Set("key4", "value", time.Duration(20) * time.Second)
+	p.option.ThresholdSize = p.Size() / 2
+	p.Optimize()
+	if reason, found := r.reason("key3"); !found || reason != ReasonCapacity {
+		t.Errorf("reason(%v) is invalid.", reason)
+	}
+}
+
+func TestOK_OnEvict_LowPriority(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	r := newEvictRecorder()
+	c := New(Option{
+			Policy: func() EvictionPolicy[string, interface{}] { return &dropPolicy{} },
+	})
+	c.OnEvict(r.record)
+
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	c.Optimize()
+	if reason, found := r.reason("key1"); !found || reason != ReasonLowPriority {
+		t.Errorf("reason(%v) is invalid.", reason)
+	}
+}
+
+func TestOK_OnEvict_Unlocked(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, int](TypedOption[string, int]{})
+	calls := 0
+	c.OnEvict(func(key string, value int, reason EvictionReason) {
+		// cache can be used in callback
+		calls++
+		c.Set("evicted:" + key, value, time.Duration(10) * time.Second)
+	})
+
+	c.Set("key", 1, time.Duration(10) * time.Second)
+	c.Del("key")
+	if v, found := c.Get("evicted:key"); calls != 1 || !found || v != 1 {
+		t.Errorf("calls(%d) or v(%v) is invalid.", calls, v)
+	}
+
+	// unset
+	c.OnEvict(nil)
+	c.Del("evicted:key")
+	if calls != 1 {
+		t.Errorf("calls(%d) is invalid.", calls)
+	}
+}
+
+func TestOK_EvictionReason_String(t *testing.T) {
+	if ReasonLowPriority.String() != "low_priority" || EvictionReason(-1).String() != "unknown" {
+		t.Errorf("string(%v) is invalid.", ReasonLowPriority)
+	}
+}
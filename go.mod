@@ -0,0 +1,3 @@
+module github.com/tico8/go-cache
+
+go 1.24
@@ -0,0 +1,171 @@
+package cache
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+)
+
+// lfuPolicy evict least frequently used items.
+// Frequency of item is AccessCount of item.
+// Items are kept in buckets of same frequency, and buckets are kept in ascending order of frequency,
+// so every operation is O(1) for usual increment of AccessCount.
+// Frequency is halved by Optimize at intervals of DecayInterval, so items which are no longer accessed age out.
+type lfuPolicy[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	buckets *list.List // *lfuBucket in ascending order of frequency
+	elements map[K]*list.Element // element of lfuBucket.entries
+	newest *TypedItem[K, V]
+	decayed time.Time
+}
+
+type lfuBucket[K comparable, V any] struct {
+	freq int64
+	entries *list.List // *lfuEntry, front is most recently used
+}
+
+type lfuEntry[K comparable, V any] struct {
+	item *TypedItem[K, V]
+	bucket *list.Element // element of lfuPolicy.buckets
+}
+
+func newLFUPolicy[K comparable, V any](opt *TypedOption[K, V]) *lfuPolicy[K, V] {
+	return &lfuPolicy[K, V]{
+		option: opt,
+		buckets: list.New(),
+		elements: map[K]*list.Element{},
+		decayed: time.Now(),
+	}
+}
+
+func (p *lfuPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	entry := &lfuEntry[K, V]{item: item}
+	p.add(entry, p.buckets.Front(), atomic.LoadInt64(&item.AccessCount))
+	p.newest = item
+}
+
+func (p *lfuPolicy[K, V]) Access(item *TypedItem[K, V]) {
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*lfuEntry[K, V])
+	bucket := entry.bucket.Value.(*lfuBucket[K, V])
+	freq := atomic.LoadInt64(&item.AccessCount)
+	if freq <= bucket.freq {
+		bucket.entries.MoveToFront(e)
+		return
+	}
+
+	from := p.remove(entry, e)
+	p.add(entry, from, freq)
+}
+
+func (p *lfuPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	p.remove(e.Value.(*lfuEntry[K, V]), e)
+	delete(p.elements, item.Key)
+	if p.newest == item {
+		p.newest = nil
+	}
+}
+
+func (p *lfuPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	order = make([]*TypedItem[K, V], 0, len(p.elements))
+	for b := p.buckets.Front(); b != nil; b = b.Next() {
+		entries := b.Value.(*lfuBucket[K, V]).entries
+		for e := entries.Back(); e != nil; e = e.Prev() {
+			order = append(order, e.Value.(*lfuEntry[K, V]).item)
+		}
+	}
+	return nil, order
+}
+
+// Victim get least frequently used item.
+// The newest item is not evicted as long as there is other item,
+// because it has not had a chance to be accessed yet.
+func (p *lfuPolicy[K, V]) Victim() (item *TypedItem[K, V], found bool) {
+	b := p.buckets.Front()
+	if b == nil {
+		return nil, false
+	}
+	e := b.Value.(*lfuBucket[K, V]).entries.Back()
+	if e.Value.(*lfuEntry[K, V]).item == p.newest {
+		if e.Prev() != nil {
+			e = e.Prev()
+		} else if b.Next() != nil {
+			e = b.Next().Value.(*lfuBucket[K, V]).entries.Back()
+		}
+	}
+	return e.Value.(*lfuEntry[K, V]).item, true
+}
+
+// Age halve frequency of all items.
+// Order of items is kept, and buckets of same frequency are merged.
+// param now - current time
+func (p *lfuPolicy[K, V]) Age(now time.Time) {
+	if now.Sub(p.decayed) < p.option.DecayInterval {
+		return
+	}
+	p.decayed = now
+
+	var prev *lfuBucket[K, V]
+	for b := p.buckets.Front(); b != nil; {
+		next := b.Next()
+		bucket := b.Value.(*lfuBucket[K, V])
+		bucket.freq /= 2
+		for e := bucket.entries.Front(); e != nil; e = e.Next() {
+			atomic.StoreInt64(&e.Value.(*lfuEntry[K, V]).item.AccessCount, bucket.freq)
+		}
+
+		if prev != nil && prev.freq == bucket.freq {
+			// more frequently used items are moved to front of lower bucket
+			for e := bucket.entries.Back(); e != nil; e = e.Prev() {
+				entry := e.Value.(*lfuEntry[K, V])
+				entry.bucket = b.Prev()
+				p.elements[entry.item.Key] = prev.entries.PushFront(entry)
+			}
+			p.buckets.Remove(b)
+		} else {
+			prev = bucket
+		}
+		b = next
+	}
+	Debug("lfu aged. buckets = %d", p.buckets.Len())
+}
+
+// add add entry to bucket of freq.
+// param entry - entry of item
+// param from - bucket to start search, whose frequency is freq or less
+// param freq - frequency of item
+func (p *lfuPolicy[K, V]) add(entry *lfuEntry[K, V], from *list.Element, freq int64) {
+	b := from
+	for b != nil && b.Value.(*lfuBucket[K, V]).freq < freq {
+		b = b.Next()
+	}
+	if b == nil {
+		b = p.buckets.PushBack(&lfuBucket[K, V]{freq: freq, entries: list.New()})
+	} else if b.Value.(*lfuBucket[K, V]).freq != freq {
+		b = p.buckets.InsertBefore(&lfuBucket[K, V]{freq: freq, entries: list.New()}, b)
+	}
+	entry.bucket = b
+	p.elements[entry.item.Key] = b.Value.(*lfuBucket[K, V]).entries.PushFront(entry)
+}
+
+// remove remove entry from its bucket.
+// Empty bucket is removed.
+// return arg1 - bucket next to the removed entry
+func (p *lfuPolicy[K, V]) remove(entry *lfuEntry[K, V], e *list.Element) *list.Element {
+	b := entry.bucket
+	bucket := b.Value.(*lfuBucket[K, V])
+	bucket.entries.Remove(e)
+	if bucket.entries.Len() == 0 {
+		next := b.Next()
+		p.buckets.Remove(b)
+		return next
+	}
+	return b
+}
@@ -0,0 +1,111 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOK_LFU_EvictOnSet(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLFU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size() * 3
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	_, _ = c.Get("key1")
+	_, _ = c.Get("key1")
+	_, _ = c.Get("key3")
+
+	// key2 is least frequently used
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+	if _, found := c.GetItem("key2"); found {
+		t.Errorf("key(%v) is not evicted.", "key2")
+	}
+	// newest key4 is not evicted, key3 is least frequently used
+	c.Set("key5", 5, time.Duration(10) * time.Second)
+	if _, found := c.GetItem("key4"); found {
+		t.Errorf("key(%v) is not evicted.", "key4")
+	}
+	for _, key := range []string{"key1", "key3", "key5"} {
+		if _, found := c.GetItem(key); !found {
+			t.Errorf("key(%v) is evicted.", key)
+		}
+	}
+}
+
+func TestOK_LFU_Age(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLFU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	for i := 0; i < 4; i++ {
+		_, _ = c.Get("key1")
+	}
+	for i := 0; i < 3; i++ {
+		_, _ = c.Get("key2")
+	}
+	_, _ = c.Get("key3")
+
+	// 4, 3, 1 => 2, 1, 0
+	c.Optimize()
+	expected := map[string]int64{"key1": 2, "key2": 1, "key3": 0}
+	for key, count := range expected {
+		item, _ := c.GetItem(key)
+		if item.AccessCount != count {
+			t.Errorf("access count(%d) of %v is invalid. expected = %d", item.AccessCount, key, count)
+		}
+	}
+
+	// 2, 1, 0 => 1, 0, 0 and buckets of 0 are merged
+	c.Optimize()
+	p := c.shards[0].policy.(*lfuPolicy[string, int64])
+	if p.buckets.Len() != 2 {
+		t.Errorf("buckets(%d) is invalid. expected = %d", p.buckets.Len(), 2)
+	}
+	_, order := p.Compact(nil)
+	if len(order) != 3 || order[0].Key != "key3" || order[1].Key != "key2" || order[2].Key != "key1" {
+		t.Errorf("order of eviction is invalid.")
+	}
+
+	// aged item is used again
+	_, _ = c.Get("key3")
+	_, _ = c.Get("key3")
+	_, order = p.Compact(nil)
+	if order[2].Key != "key3" {
+		t.Errorf("key(%v) is not most frequently used.", order[2].Key)
+	}
+}
+
+func TestOK_LFU_DecayInterval(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLFU,
+			DecayInterval: time.Duration(1) * time.Hour,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	_, _ = c.Get("key1")
+	_, _ = c.Get("key1")
+	c.Optimize()
+
+	item, _ := c.GetItem("key1")
+	if item.AccessCount != 2 {
+		t.Errorf("access count(%d) is invalid. expected = %d", item.AccessCount, 2)
+	}
+}
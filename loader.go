@@ -0,0 +1,455 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Loader loader loads items which are not found in cache.
+type Loader[K comparable, V any] interface {
+	// Load load value of key.
+	// param key - key of item
+	// return arg1 - value of item
+	// return arg2 - expire time
+	// return arg3 - Error
+	Load(key K) (value V, expireIn time.Duration, err error)
+
+	// LoadAll load values of keys at once.
+	// param keys - keys of items
+	// return arg1 - values of keys, keys which are not found are omitted
+	// return arg2 - expire time
+	// return arg3 - Error
+	LoadAll(keys []K) (values map[K]V, expireIn time.Duration, err error)
+}
+
+// LoaderFunc adapter to use function as Loader.
+// LoadAll calls the function for each key.
+type LoaderFunc[K comparable, V any] func(key K) (V, time.Duration, error)
+
+// Load call f(key).
+func (f LoaderFunc[K, V]) Load(key K) (V, time.Duration, error) {
+	return f(key)
+}
+
+// LoadAll call f for each key, and the shortest expire time is returned.
+func (f LoaderFunc[K, V]) LoadAll(keys []K) (map[K]V, time.Duration, error) {
+	values := make(map[K]V, len(keys))
+	var expireIn time.Duration
+	for i, key := range keys {
+		value, ttl, err := f(key)
+		if err != nil {
+			return values, expireIn, err
+		}
+		if i == 0 || ttl < expireIn {
+			expireIn = ttl
+		}
+		values[key] = value
+	}
+	return values, expireIn, nil
+}
+
+// getItem get item, and load it by Loader if it is not found.
+// Item near expiration is refreshed ahead in background, and stale item is served while it is reloaded.
+// Loaded value is returned in item of its own, even if it is evicted as soon as it is set.
+// return arg3 - If item is expired and served as stale, return true.
+func (c *cache[K, V]) getItem(key K) (item *TypedItem[K, V], found bool, stale bool) {
+	item, found = c.access(key)
+	now := time.Now()
+	if found && !item.Expired(now) {
+		c.stats.hits.Add(1)
+		c.refreshAhead(item)
+		return item, true, false
+	}
+	if found && c.servable(item, now) {
+		c.stats.hits.Add(1)
+		c.revalidate(item)
+		return item, true, true
+	}
+	c.stats.misses.Add(1)
+	if c.option.Loader == nil {
+		return nil, false, false
+	}
+	value, err := c.load(key, func() (V, time.Duration, error) {
+		return c.option.Loader.Load(key)
+	})
+	if err != nil {
+		if found && time.Now().Before(item.Expiration.Add(c.option.StaleIfError)) {
+			Warn("load error, stale item is served. key = %v error = %v", key, err)
+			atomic.StoreInt32(&item.reloadFailed, 1)
+			return item, true, true
+		}
+		Warn("load error key = %v error = %v", key, err)
+		return nil, false, false
+	}
+	return &TypedItem[K, V]{Key: key, Object: value}, true, false
+}
+
+// servable check stale item can be served.
+// Stale item is served within StaleWhileRevalidate after expiration,
+// and within StaleIfError after expiration if reloading is failed.
+// param item - expired item
+// param now - current time
+// return arg1 - If item can be served, return true.
+func (c *cache[K, V]) servable(item *TypedItem[K, V], now time.Time) bool {
+	if now.Before(item.Expiration.Add(c.option.StaleWhileRevalidate)) {
+		return true
+	}
+	return atomic.LoadInt32(&item.reloadFailed) == 1 && now.Before(item.Expiration.Add(c.option.StaleIfError))
+}
+
+// GetAll get values of keys from cache.
+// If Loader is set in option, items which are not found are loaded by LoadAll at once.
+// Keys being loaded by concurrent Get, GetOrLoad or GetAll are not loaded again, and their results are waited.
+// If LoadAll fails, stale items within StaleIfError are returned with the error.
+// param keys - keys of items
+// return arg1 - values of keys, keys which are not found are omitted
+// return arg2 - Error of Loader
+func (c *cache[K, V]) GetAll(keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	var misses []K
+	stales := map[K]*TypedItem[K, V]{}
+	for _, key := range keys {
+		item, found := c.access(key)
+		now := time.Now()
+		switch {
+		case found && !item.Expired(now):
+			c.stats.hits.Add(1)
+			c.refreshAhead(item)
+			values[key] = c.value(item)
+		case found && c.servable(item, now):
+			c.stats.hits.Add(1)
+			c.revalidate(item)
+			values[key] = c.value(item)
+		default:
+			c.stats.misses.Add(1)
+			misses = append(misses, key)
+			if found {
+				stales[key] = item
+			}
+		}
+	}
+	if len(misses) == 0 || c.option.Loader == nil {
+		return values, nil
+	}
+
+	// keys being loaded by other calls are not loaded again, but waited
+	loaded, err := c.loads.doAll(misses, func(keys []K) (map[K]V, error) {
+		return c.loadAll(keys)
+	})
+	for key, value := range loaded {
+		values[key] = value
+	}
+	if err != nil {
+		now := time.Now()
+		for key, item := range stales {
+			if _, found := values[key]; !found && now.Before(item.Expiration.Add(c.option.StaleIfError)) {
+				atomic.StoreInt32(&item.reloadFailed, 1)
+				values[key] = c.value(item)
+			}
+		}
+	}
+	return values, err
+}
+
+// loadAll load values of keys by LoadAll, and set them to cache.
+// param keys - keys of items
+// return arg1 - values of keys, keys which are not found are omitted
+// return arg2 - Error of Loader
+func (c *cache[K, V]) loadAll(keys []K) (map[K]V, error) {
+	values := make(map[K]V, len(keys))
+	var misses []K
+	now := time.Now()
+	for _, key := range keys {
+		// loaded by other call just before
+		if item, found := c.GetItem(key); found && !item.Expired(now) {
+			values[key] = c.value(item)
+		} else {
+			misses = append(misses, key)
+		}
+	}
+	if len(misses) == 0 {
+		return values, nil
+	}
+
+	c.stats.loads.Add(int64(len(misses)))
+	loaded, expireIn, err := c.option.Loader.LoadAll(misses)
+	if err != nil {
+		c.stats.loadErrors.Add(int64(len(misses)))
+		Debug("load error keys = %v error = %v", misses, err)
+		return values, err
+	}
+	for key, value := range loaded {
+		if err := c.check(value); err != nil {
+			return values, err
+		}
+		if err := c.put(key, value, expireIn); err != nil {
+			return values, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// refreshAhead refresh item by Loader in background, if it is accessed within RefreshAhead of its expire time.
+// Item accessed less than ThresholdAccessCount times is not refreshed, and item is refreshed only once at a time.
+// Readers get current item until refreshed item is set.
+// param item - item which is accessed
+func (c *cache[K, V]) refreshAhead(item *TypedItem[K, V]) {
+	if c.option.Loader == nil || c.option.RefreshAhead <= 0 || item.ttl <= 0 {
+		return
+	}
+	if c.option.ThresholdAccessCount != 0 && atomic.LoadInt64(&item.AccessCount) < c.option.ThresholdAccessCount {
+		return
+	}
+	if time.Until(*item.Expiration) > time.Duration(float64(item.ttl) * c.option.RefreshAhead) {
+		return
+	}
+	c.revalidate(item)
+}
+
+// revalidate refresh item by Loader in background, unless it is being refreshed.
+// param item - item to be refreshed
+func (c *cache[K, V]) revalidate(item *TypedItem[K, V]) {
+	if c.option.Loader == nil || !atomic.CompareAndSwapInt32(&item.refreshing, 0, 1) {
+		return
+	}
+	go c.refresh(item)
+}
+
+// refresh load item again, and replace it unless it is set or deleted meanwhile.
+// Loading is shared with concurrent loads of same key.
+// If Loader fails or panics, item is kept and refreshed on next access, and stale item is served within StaleIfError.
+// param item - item to be refreshed
+func (c *cache[K, V]) refresh(item *TypedItem[K, V]) {
+	// panic of Loader in background must not stop the process
+	defer func() {
+		if r := recover(); r != nil {
+			c.stats.loadErrors.Add(1)
+			c.refreshFailed(item, fmt.Errorf("load panic: %v", r))
+		}
+	}()
+	_, err := c.loads.do(item.Key, func() (V, error) {
+		c.stats.loads.Add(1)
+		value, expireIn, err := c.option.Loader.Load(item.Key)
+		if err == nil {
+			err = c.check(value)
+		}
+		var refreshed *TypedItem[K, V]
+		if err == nil {
+			refreshed, err = c.newItem(item.Key, value, expireIn)
+		}
+		if err != nil {
+			c.stats.loadErrors.Add(1)
+			return value, err
+		}
+		if c.shard(item.Key).replace(item, refreshed) {
+			Debug("refreshed key = %v", item.Key)
+			c.emitSet(item, refreshed)
+		}
+		return value, nil
+	})
+	if err != nil {
+		c.refreshFailed(item, err)
+	}
+}
+
+// refreshFailed mark item as failed to be reloaded, so that it is refreshed on next access.
+// param item - item to be refreshed
+// param err - Error of refreshing
+func (c *cache[K, V]) refreshFailed(item *TypedItem[K, V], err error) {
+	Warn("refresh error key = %v error = %v", item.Key, err)
+	atomic.StoreInt32(&item.reloadFailed, 1)
+	atomic.StoreInt32(&item.refreshing, 0)
+}
+
+// GetOrLoad get value from cache, or load it by loader if it is not found.
+// Concurrent calls for same key share a single call of loader.
+// Loaded value is set to cache without writing to Writer, but error of loader is not cached.
+// Stale item is regarded as not found.
+// param key - key of item
+// param loader - function to load value, which returns value, expire time and error
+// return arg1 - value of item
+// return arg2 - Error of loader
+func (c *cache[K, V]) GetOrLoad(key K, loader func() (V, time.Duration, error)) (value V, err error) {
+	if item, found := c.access(key); found && !item.Expired(time.Now()) {
+		c.stats.hits.Add(1)
+		return c.value(item), nil
+	}
+	c.stats.misses.Add(1)
+	return c.load(key, loader)
+}
+
+// load load value by loader, and set it to cache.
+// Concurrent calls for same key share a single call of loader.
+// param key - key of item
+// param loader - function to load value
+// return arg1 - value of item
+// return arg2 - Error of loader
+func (c *cache[K, V]) load(key K, loader func() (V, time.Duration, error)) (V, error) {
+	return c.loads.do(key, func() (V, error) {
+		// loaded by other call just before
+		if item, found := c.GetItem(key); found && !item.Expired(time.Now()) {
+			return c.value(item), nil
+		}
+		c.stats.loads.Add(1)
+		value, expireIn, err := loader()
+		if err != nil {
+			c.stats.loadErrors.Add(1)
+			Debug("load error key = %v error = %v", key, err)
+			return value, err
+		}
+		if err := c.check(value); err != nil {
+			return value, err
+		}
+		if err := c.put(key, value, expireIn); err != nil {
+			return value, err
+		}
+		return value, nil
+	})
+}
+
+// loadGroup deduplicate concurrent loading of same key.
+type loadGroup[K comparable, V any] struct {
+	mu sync.Mutex
+	calls map[K]*loadCall[V]
+}
+
+// errNotLoaded Error of key which is omitted by LoadAll
+var errNotLoaded = errors.New("key is not found by LoadAll")
+
+type loadCall[V any] struct {
+	wg sync.WaitGroup
+	value V
+	err error
+}
+
+// do call fn only once for concurrent calls of same key.
+// If fn panics, waiting calls get error, and the panic is propagated to the calling goroutine.
+// param key - key of item
+// param fn - function to load value
+// return arg1 - value returned by fn
+// return arg2 - Error returned by fn
+func (g *loadGroup[K, V]) do(key K, fn func() (V, error)) (V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*loadCall[V]{}
+	}
+	if call, found := g.calls[key]; found {
+		g.mu.Unlock()
+		call.wg.Wait()
+		return call.value, call.err
+	}
+	call := &loadCall[V]{}
+	call.wg.Add(1)
+	g.calls[key] = call
+	g.mu.Unlock()
+
+	defer func() {
+		r := recover()
+		if r != nil {
+			call.err = fmt.Errorf("load panic key = %v: %v", key, r)
+		}
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		call.wg.Done()
+		if r != nil {
+			panic(r)
+		}
+	}()
+	call.value, call.err = fn()
+	return call.value, call.err
+}
+
+// doAll call fn once for keys which are not being loaded, and wait for other calls loading the rest of keys.
+// Concurrent calls of do for keys loaded by fn share its result, and get Error for keys omitted by fn.
+// param keys - keys of items
+// param fn - function to load values of keys, which are not being loaded
+// return arg1 - values of keys, keys which are not found are omitted
+// return arg2 - Error returned by fn or other calls
+func (g *loadGroup[K, V]) doAll(keys []K, fn func(keys []K) (map[K]V, error)) (map[K]V, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[K]*loadCall[V]{}
+	}
+	owned := map[K]*loadCall[V]{}
+	waiting := map[K]*loadCall[V]{}
+	var ownedKeys []K
+	for _, key := range keys {
+		if _, found := owned[key]; found {
+			continue
+		}
+		if call, found := g.calls[key]; found {
+			waiting[key] = call
+			continue
+		}
+		call := &loadCall[V]{}
+		call.wg.Add(1)
+		g.calls[key] = call
+		owned[key] = call
+		ownedKeys = append(ownedKeys, key)
+	}
+	g.mu.Unlock()
+
+	values := make(map[K]V, len(keys))
+	var err error
+	if len(ownedKeys) > 0 {
+		err = g.callAll(ownedKeys, owned, fn, values)
+	}
+	for key, call := range waiting {
+		call.wg.Wait()
+		switch {
+		case call.err == nil:
+			values[key] = call.value
+		case errors.Is(call.err, errNotLoaded):
+		case err == nil:
+			err = call.err
+		}
+	}
+	return values, err
+}
+
+// callAll call fn for keys, and pass its result to calls of keys.
+// If fn panics, waiting calls get error, and the panic is propagated to the calling goroutine.
+// param keys - keys of items
+// param calls - calls of keys
+// param fn - function to load values of keys
+// param values - map to which values returned by fn are copied
+// return arg1 - Error returned by fn
+func (g *loadGroup[K, V]) callAll(keys []K, calls map[K]*loadCall[V], fn func(keys []K) (map[K]V, error), values map[K]V) (err error) {
+	var loaded map[K]V
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = fmt.Errorf("load panic keys = %v: %v", keys, r)
+		}
+		g.mu.Lock()
+		for key, call := range calls {
+			value, found := loaded[key]
+			switch {
+			case found:
+				call.value = value
+			case err != nil:
+				call.err = err
+			default:
+				call.err = errNotLoaded
+			}
+			delete(g.calls, key)
+		}
+		g.mu.Unlock()
+		for _, call := range calls {
+			call.wg.Done()
+		}
+		if r != nil {
+			panic(r)
+		}
+	}()
+	loaded, err = fn(keys)
+	for key, value := range loaded {
+		values[key] = value
+	}
+	return err
+}
@@ -0,0 +1,599 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOK_GetOrLoad_Singleflight(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+
+	var calls int32
+	start := make(chan bool)
+	loader := func() (interface{}, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(time.Duration(10) * time.Millisecond)
+		return "loaded", time.Duration(10) * time.Second, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			v, err := c.GetOrLoad("key", loader)
+			if err != nil || v != "loaded" {
+				t.Errorf("v(%v) is invalid. error = %v", v, err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("calls(%d) of loader is invalid. expected = %d", calls, 1)
+	}
+	v, found := c.Get("key")
+	if !found || *v != "loaded" {
+		t.Errorf("loaded value is not set.")
+	}
+
+	// found in cache
+	_, _ = c.GetOrLoad("key", loader)
+	if calls != 1 {
+		t.Errorf("calls(%d) of loader is invalid. expected = %d", calls, 1)
+	}
+}
+
+func TestNG_GetOrLoad_Error(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, int](TypedOption[string, int]{})
+
+	calls := 0
+	_, err := c.GetOrLoad("key", func() (int, time.Duration, error) {
+		calls++
+		return 0, 0, errors.New("load error")
+	})
+	if err == nil {
+		t.Errorf("expected error.")
+	}
+	if _, found := c.Get("key"); found {
+		t.Errorf("error is cached.")
+	}
+
+	// error is not cached, so loader is called again
+	v, err := c.GetOrLoad("key", func() (int, time.Duration, error) {
+		calls++
+		return 1, time.Duration(10) * time.Second, nil
+	})
+	if err != nil || v != 1 || calls != 2 {
+		t.Errorf("v(%v) is invalid. calls = %d error = %v", v, calls, err)
+	}
+}
+
+func TestNG_GetOrLoad_Panic(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, string](TypedOption[string, string]{})
+	started := make(chan bool)
+	release := make(chan bool)
+	panicked := make(chan interface{})
+	go func() {
+		defer func() {
+			panicked <- recover()
+		}()
+		c.GetOrLoad("key", func() (string, time.Duration, error) {
+			close(started)
+			<-release
+			panic("loader panic")
+		})
+	}()
+	<-started
+
+	// waiting call gets error, not zero value as loaded
+	result := make(chan error)
+	go func() {
+		_, err := c.GetOrLoad("key", func() (string, time.Duration, error) {
+			return "value", time.Duration(10) * time.Second, nil
+		})
+		result <- err
+	}()
+	time.Sleep(time.Duration(50) * time.Millisecond)
+	close(release)
+
+	if r := <-panicked; r != "loader panic" {
+		t.Errorf("panic(%v) is not propagated.", r)
+	}
+	if err := <-result; err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.Get("key"); found {
+		t.Errorf("key is set.")
+	}
+}
+
+func TestNG_GetOrLoad_Unsupported(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	_, err := c.GetOrLoad("key", func() (interface{}, time.Duration, error) {
+		return make(chan int), 0, nil
+	})
+	if err == nil {
+		t.Errorf("expected error.")
+	}
+	if _, found := c.Get("key"); found {
+		t.Errorf("unsupported value is cached.")
+	}
+}
+
+// testLoader count calls of Load and LoadAll.
+type testLoader struct {
+	loads int32
+	loadAlls int32
+	keys []string
+}
+
+func (l *testLoader) Load(key string) (interface{}, time.Duration, error) {
+	atomic.AddInt32(&l.loads, 1)
+	if key == "badKey" {
+		return nil, 0, errors.New("not found")
+	}
+	return "loaded:" + key, time.Duration(10) * time.Second, nil
+}
+
+func (l *testLoader) LoadAll(keys []string) (map[string]interface{}, time.Duration, error) {
+	atomic.AddInt32(&l.loadAlls, 1)
+	l.keys = keys
+	values := map[string]interface{}{}
+	for _, key := range keys {
+		if key != "badKey" {
+			values[key] = "loaded:" + key
+		}
+	}
+	return values, time.Duration(10) * time.Second, nil
+}
+
+func TestOK_Loader_Get(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	loader := &testLoader{}
+	c := New(Option{Loader: loader})
+
+	v, found := c.Get("key")
+	if !found || *v != "loaded:key" {
+		t.Errorf("key(%v) is not loaded.", "key")
+		t.FailNow()
+	}
+	_, _ = c.Get("key")
+	if loader.loads != 1 {
+		t.Errorf("loads(%d) is invalid. expected = %d", loader.loads, 1)
+	}
+
+	// error of loader is miss
+	if _, found := c.Get("badKey"); found {
+		t.Errorf("key(%v) is found.", "badKey")
+	}
+	if _, found := c.GetItem("badKey"); found {
+		t.Errorf("key(%v) is cached.", "badKey")
+	}
+}
+
+func TestOK_Loader_Get_Evicted(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// loaded item is evicted as soon as it is set
+	loader := &testLoader{}
+	c := New(Option{Loader: loader, ThresholdSize: 1, Eviction: EvictLRU})
+
+	v, found := c.Get("key")
+	if !found || *v != "loaded:key" {
+		t.Errorf("v(%v) is invalid. found = %v", v, found)
+	}
+	if _, found := c.GetItem("key"); found {
+		t.Errorf("key(%v) is not evicted.", "key")
+	}
+}
+
+func TestOK_Loader_GetAll(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	loader := &testLoader{}
+	c := New(Option{Loader: loader})
+	c.Set("key1", "cached", time.Duration(10) * time.Second)
+
+	values, err := c.GetAll([]string{"key1", "key2", "key3", "badKey"})
+	if err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if loader.loadAlls != 1 || loader.loads != 0 || len(loader.keys) != 3 {
+		t.Errorf("loadAlls(%d) loads(%d) keys(%v) are invalid.", loader.loadAlls, loader.loads, loader.keys)
+	}
+	if len(values) != 3 || values["key1"] != "cached" || values["key2"] != "loaded:key2" {
+		t.Errorf("values(%v) are invalid.", values)
+	}
+	if _, found := c.GetItem("key3"); !found {
+		t.Errorf("key(%v) is not set.", "key3")
+	}
+}
+
+// blockingLoader testLoader which blocks until released.
+type blockingLoader struct {
+	testLoader
+	calls chan bool
+	release chan bool
+}
+
+func (l *blockingLoader) Load(key string) (interface{}, time.Duration, error) {
+	l.calls <- true
+	<-l.release
+	return l.testLoader.Load(key)
+}
+
+func (l *blockingLoader) LoadAll(keys []string) (map[string]interface{}, time.Duration, error) {
+	l.calls <- true
+	<-l.release
+	return l.testLoader.LoadAll(keys)
+}
+
+func TestOK_Loader_GetAll_Dedup(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	loader := &blockingLoader{calls: make(chan bool, 2), release: make(chan bool)}
+	c := New(Option{Loader: loader})
+
+	// GetAll waits for Get loading key1
+	go c.Get("key1")
+	<-loader.calls
+	done := make(chan map[string]interface{})
+	go func() {
+		values, _ := c.GetAll([]string{"key1", "key2", "key2"})
+		done <- values
+	}()
+	<-loader.calls
+	close(loader.release)
+	values := <-done
+	if len(values) != 2 || values["key1"] != "loaded:key1" || values["key2"] != "loaded:key2" {
+		t.Errorf("values(%v) are invalid.", values)
+	}
+	if loader.loads != 1 || loader.loadAlls != 1 || len(loader.keys) != 1 {
+		t.Errorf("loads(%d) loadAlls(%d) keys(%v) are invalid.", loader.loads, loader.loadAlls, loader.keys)
+	}
+
+	// Get waits for GetAll loading key3
+	loader.release = make(chan bool)
+	go c.GetAll([]string{"key3", "badKey"})
+	<-loader.calls
+	results := make(chan bool, 2)
+	for _, key := range []string{"key3", "badKey"} {
+		go func(key string) {
+			_, found := c.Get(key)
+			results <- found
+		}(key)
+	}
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	close(loader.release)
+	found := 0
+	for i := 0; i < 2; i++ {
+		if <-results {
+			found++
+		}
+	}
+	if found != 1 || loader.loads != 1 || loader.loadAlls != 2 {
+		t.Errorf("found(%d) loads(%d) loadAlls(%d) are invalid.", found, loader.loads, loader.loadAlls)
+	}
+	if stats := c.Stats(); stats.Loads != 4 {
+		t.Errorf("loads(%d) are invalid.", stats.Loads)
+	}
+}
+
+func TestOK_LoaderFunc(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[int, int]{
+			Loader: LoaderFunc[int, int](func(key int) (int, time.Duration, error) {
+				return key * 2, time.Duration(key) * time.Second, nil
+			}),
+		}
+	c := NewTyped[int, int](opt)
+
+	if v, found := c.Get(2); !found || v != 4 {
+		t.Errorf("v(%v) is invalid. expected = %v", v, 4)
+	}
+	values, err := c.GetAll([]int{2, 3, 5})
+	if err != nil || len(values) != 3 || values[5] != 10 {
+		t.Errorf("values(%v) are invalid. error = %v", values, err)
+	}
+	item, _ := c.GetItem(3)
+	if item.Expiration.After(time.Now().Add(time.Duration(3) * time.Second)) {
+		t.Errorf("expiration(%v) is not the shortest.", item.Expiration)
+	}
+}
+
+func TestOK_RefreshAhead(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var loads int32
+	opt := TypedOption[string, int32]{
+			RefreshAhead: 0.5,
+			Loader: LoaderFunc[string, int32](func(key string) (int32, time.Duration, error) {
+				return atomic.AddInt32(&loads, 1), time.Duration(200) * time.Millisecond, nil
+			}),
+		}
+	c := NewTyped[string, int32](opt)
+
+	if v, _ := c.Get("key"); v != 1 {
+		t.Errorf("v(%v) is invalid. expected = %v", v, 1)
+	}
+	// not near expiration
+	c.Get("key")
+	if atomic.LoadInt32(&loads) != 1 {
+		t.Errorf("loads(%d) is invalid. expected = %d", loads, 1)
+	}
+
+	// current value is got while refreshing
+	time.Sleep(time.Duration(120) * time.Millisecond)
+	if v, _ := c.Get("key"); v != 1 {
+		t.Errorf("v(%v) is invalid. expected = %v", v, 1)
+	}
+	time.Sleep(time.Duration(50) * time.Millisecond)
+	if v, found := c.Get("key"); !found || v != 2 {
+		t.Errorf("v(%v) is not refreshed.", v)
+	}
+	if atomic.LoadInt32(&loads) != 2 {
+		t.Errorf("loads(%d) is invalid. expected = %d", loads, 2)
+	}
+}
+
+func TestOK_RefreshAhead_Set(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	start := make(chan bool)
+	opt := TypedOption[string, string]{
+			RefreshAhead: 1,
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				<-start
+				return "loaded", time.Duration(10) * time.Second, nil
+			}),
+		}
+	c := NewTyped[string, string](opt)
+
+	c.Set("key", "cached", time.Duration(10) * time.Second)
+	c.Get("key")
+	// set while refreshing is not overwritten
+	c.Set("key", "set", time.Duration(10) * time.Second)
+	close(start)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if v, _ := c.Get("key"); v != "set" {
+		t.Errorf("v(%v) is overwritten by refresh.", v)
+	}
+}
+
+func TestNG_RefreshAhead_Error(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var loads int32
+	opt := TypedOption[string, string]{
+			RefreshAhead: 1,
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				atomic.AddInt32(&loads, 1)
+				return "", 0, errors.New("load error")
+			}),
+		}
+	c := NewTyped[string, string](opt)
+
+	c.Set("key", "cached", time.Duration(10) * time.Second)
+	c.Get("key")
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	// failed refresh keeps current value
+	if v, found := c.Get("key"); !found || v != "cached" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if atomic.LoadInt32(&loads) != 2 {
+		t.Errorf("loads(%d) is invalid. expected = %d", loads, 2)
+	}
+}
+
+func TestNG_RefreshAhead_Panic(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var loads int32
+	opt := TypedOption[string, string]{
+			RefreshAhead: 1,
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				atomic.AddInt32(&loads, 1)
+				panic("load panic")
+			}),
+		}
+	c := NewTyped[string, string](opt)
+
+	c.Set("key", "cached", time.Duration(10) * time.Second)
+	c.Get("key")
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	// panic in background is recovered, and current value is kept
+	if v, found := c.Get("key"); !found || v != "cached" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if atomic.LoadInt32(&loads) != 2 || c.Stats().LoadErrors != 2 {
+		t.Errorf("loads(%d) loadErrors(%d) are invalid.", loads, c.Stats().LoadErrors)
+	}
+}
+
+func TestNG_Loader_Nil(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{
+			RefreshAhead: 1,
+			Loader: LoaderFunc[string, interface{}](func(key string) (interface{}, time.Duration, error) {
+				return nil, time.Duration(10) * time.Second, nil
+			}),
+		})
+
+	if err := c.Set("key", nil, time.Duration(10) * time.Second); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.Get("missing"); found {
+		t.Errorf("nil is loaded.")
+	}
+
+	// refresh of nil keeps current value
+	c.Set("key", "cached", time.Duration(10) * time.Second)
+	c.Get("key")
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if v, found := c.Get("key"); !found || *v != "cached" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+}
+
+func TestOK_Revalidate_Shared(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	calls := make(chan bool, 1)
+	release := make(chan bool)
+	opt := TypedOption[string, string]{
+			StaleWhileRevalidate: time.Second,
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				calls <- true
+				<-release
+				return "reloaded", time.Duration(10) * time.Second, nil
+			}),
+		}
+	c := NewTyped[string, string](opt)
+	c.Set("key", "cached", time.Duration(10) * time.Millisecond)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+
+	// stale item is reloaded in background, and GetOrLoad waits for it
+	if v, _, stale := c.GetStale("key"); !stale || v != "cached" {
+		t.Errorf("v(%v) stale(%v) is invalid.", v, stale)
+	}
+	<-calls
+	done := make(chan string)
+	go func() {
+		v, _ := c.GetOrLoad("key", func() (string, time.Duration, error) {
+			t.Errorf("loader is called again.")
+			return "", 0, nil
+		})
+		done <- v
+	}()
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	close(release)
+	if v := <-done; v != "reloaded" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	if stats := c.Stats(); stats.Loads != 1 {
+		t.Errorf("loads(%d) are invalid.", stats.Loads)
+	}
+}
+
+func TestOK_StaleWhileRevalidate(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var loads int32
+	opt := TypedOption[string, int32]{
+			StaleWhileRevalidate: time.Second,
+			Loader: LoaderFunc[string, int32](func(key string) (int32, time.Duration, error) {
+				return atomic.AddInt32(&loads, 1), time.Duration(50) * time.Millisecond, nil
+			}),
+		}
+	c := NewTyped[string, int32](opt)
+
+	if v, found, stale := c.GetStale("key"); !found || stale || v != 1 {
+		t.Errorf("v(%v) found(%v) stale(%v) is invalid.", v, found, stale)
+	}
+
+	// stale value is served, and reloaded in background
+	time.Sleep(time.Duration(70) * time.Millisecond)
+	if v, found, stale := c.GetStale("key"); !found || !stale || v != 1 {
+		t.Errorf("v(%v) found(%v) stale(%v) is invalid.", v, found, stale)
+	}
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if v, found, stale := c.GetStale("key"); !found || stale || v != 2 {
+		t.Errorf("v(%v) found(%v) stale(%v) is invalid.", v, found, stale)
+	}
+
+	// stale item is kept by Optimize
+	time.Sleep(time.Duration(70) * time.Millisecond)
+	c.Optimize()
+	if _, found := c.GetItem("key"); !found {
+		t.Errorf("stale item is deleted.")
+	}
+}
+
+func TestOK_StaleIfError(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var failed atomic.Bool
+	opt := TypedOption[string, string]{
+			StaleWhileRevalidate: time.Duration(50) * time.Millisecond,
+			StaleIfError: time.Duration(200) * time.Millisecond,
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				if failed.Load() {
+					return "", 0, errors.New("load error")
+				}
+				return "loaded:" + key, time.Duration(30) * time.Millisecond, nil
+			}),
+		}
+	c := NewTyped[string, string](opt)
+
+	c.Get("key1")
+	c.Get("key2")
+	failed.Store(true)
+
+	// reloading within grace period fails
+	time.Sleep(time.Duration(40) * time.Millisecond)
+	if _, _, stale := c.GetStale("key1"); !stale {
+		t.Errorf("key1 is not stale.")
+	}
+	time.Sleep(time.Duration(60) * time.Millisecond)
+
+	// stale value is served after grace period while reloading fails
+	for _, key := range []string{"key1", "key2"} {
+		if v, found, stale := c.GetStale(key); !found || !stale || v != "loaded:" + key {
+			t.Errorf("v(%v) found(%v) stale(%v) is invalid.", v, found, stale)
+		}
+	}
+
+	// not served over hard limit
+	time.Sleep(time.Duration(200) * time.Millisecond)
+	if _, found := c.Get("key1"); found {
+		t.Errorf("stale item is served over StaleIfError.")
+	}
+}
+
+func TestNG_Stale_Disabled(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, string](TypedOption[string, string]{})
+	c.Set("key", "value", time.Duration(10) * time.Millisecond)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	if _, found, stale := c.GetStale("key"); found || stale {
+		t.Errorf("found(%v) stale(%v) is invalid.", found, stale)
+	}
+}
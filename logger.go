@@ -4,6 +4,7 @@ import (
 	"os"
 	log "log"
 	"fmt"
+	"sync/atomic"
 )
 
 const (
@@ -11,7 +12,7 @@ const (
 	Default = "default"
 )
 
-var enabled = false
+var enabled atomic.Bool // read by background goroutines of cache
 var lmap = map[string]Logger{}
 var std = log.New(os.Stderr, "", log.Ldate | log.Ltime | log.Lshortfile)
 
@@ -28,21 +29,21 @@ type Logger interface {
 // EnableLogger enable logger
 // param enable - true is enable
 func EnableLogger(enable bool) {
-	enabled = enable
+	enabled.Store(enable)
 }
 
 // SetLogger set logger
 // param logger - instance of logger
 // return arg1 - Error
 func SetLogger(logger Logger) error {
-	enabled = true
+	enabled.Store(true)
 	lmap["default"] = logger
 	return nil
 }
 
 // Trace output trace log
 func Trace(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
@@ -54,7 +55,7 @@ func Trace(format string, v ...interface{}) {
 
 // Debug output debug log
 func Debug(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
@@ -66,7 +67,7 @@ func Debug(format string, v ...interface{}) {
 
 // Info output information log
 func Info(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
@@ -78,7 +79,7 @@ func Info(format string, v ...interface{}) {
 
 // Warn output warning log
 func Warn(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
@@ -90,7 +91,7 @@ func Warn(format string, v ...interface{}) {
 
 // Error output error log
 func Error(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
@@ -102,7 +103,7 @@ func Error(format string, v ...interface{}) {
 
 // Critical output critical log
 func Critical(format string, v ...interface{}) {
-	if (!enabled) {
+	if (!enabled.Load()) {
 		return
 	}
 	if lmap[Default] != nil {
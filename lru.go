@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"container/list"
+)
+
+// lruPolicy evict least recently used items.
+// Items are kept in doubly linked list in order of recency, so every operation is O(1).
+type lruPolicy[K comparable, V any] struct {
+	list *list.List
+	elements map[K]*list.Element
+}
+
+func newLRUPolicy[K comparable, V any]() *lruPolicy[K, V] {
+	return &lruPolicy[K, V]{
+		list: list.New(),
+		elements: map[K]*list.Element{},
+	}
+}
+
+func (p *lruPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	p.elements[item.Key] = p.list.PushFront(item)
+}
+
+func (p *lruPolicy[K, V]) Access(item *TypedItem[K, V]) {
+	if e, found := p.elements[item.Key]; found {
+		p.list.MoveToFront(e)
+	}
+}
+
+func (p *lruPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+	if e, found := p.elements[item.Key]; found {
+		p.list.Remove(e)
+		delete(p.elements, item.Key)
+	}
+}
+
+func (p *lruPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	order = make([]*TypedItem[K, V], 0, p.list.Len())
+	for e := p.list.Back(); e != nil; e = e.Prev() {
+		order = append(order, e.Value.(*TypedItem[K, V]))
+	}
+	return nil, order
+}
+
+func (p *lruPolicy[K, V]) Victim() (item *TypedItem[K, V], found bool) {
+	e := p.list.Back()
+	if e == nil {
+		return nil, false
+	}
+	return e.Value.(*TypedItem[K, V]), true
+}
@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOK_LRU_EvictOnSet(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	itemSize := c.Size()
+	c.option.ThresholdSize = itemSize * 3
+
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	_, _ = c.Get("key1") // key2 is least recently used
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+
+	if _, found := c.GetItem("key2"); found {
+		t.Errorf("key(%v) is not evicted.", "key2")
+	}
+	for _, key := range []string{"key1", "key3", "key4"} {
+		if _, found := c.GetItem(key); !found {
+			t.Errorf("key(%v) is evicted.", key)
+		}
+	}
+	if c.Size() > c.option.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), c.option.ThresholdSize)
+	}
+}
+
+func TestOK_LRU_Optimize(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	_, _ = c.Get("key1")
+
+	// key2 and key3 are evicted in order of recency
+	c.option.ThresholdSize = c.Size() / 3
+	c.Optimize()
+	if _, found := c.GetItem("key1"); !found {
+		t.Errorf("key(%v) is evicted.", "key1")
+	}
+	if len(c.List()) != 1 {
+		t.Errorf("items(%v) are not evicted.", c.List())
+	}
+}
+
+func TestOK_LRU_Del(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key1", 2, time.Duration(10) * time.Second)
+	c.Del("key1")
+
+	p := c.shards[0].policy.(*lruPolicy[string, int64])
+	if p.list.Len() != 0 || len(p.elements) != 0 {
+		t.Errorf("list(%d) of policy is not empty.", p.list.Len())
+	}
+}
@@ -0,0 +1,85 @@
+// Package metrics exports statistics of cache to Prometheus.
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	cache "github.com/tico8/go-cache"
+)
+
+const (
+	// Namespace namespace of metrics
+	Namespace = "cache"
+)
+
+// Source cache whose statistics are collected.
+// Cache and TypedCache implement Source.
+type Source interface {
+	Stats() cache.Stats
+}
+
+// Collector prometheus.Collector of statistics of cache.
+// Metrics have "name" label, so that collectors of multiple caches can be registered together.
+type Collector struct {
+	source Source
+	hits *prometheus.Desc
+	misses *prometheus.Desc
+	loads *prometheus.Desc
+	loadErrors *prometheus.Desc
+	evictions *prometheus.Desc
+	items *prometheus.Desc
+	size *prometheus.Desc
+	thresholdSize *prometheus.Desc
+	optimize *prometheus.Desc
+}
+
+// NewCollector create collector of cache.
+// param name - name of cache, which is the value of "name" label
+// param source - cache
+// return arg1 - instance of Collector
+func NewCollector(name string, source Source) *Collector {
+	labels := prometheus.Labels{"name": name}
+	desc := func(metric, help string, variableLabels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(Namespace, "", metric), help, variableLabels, labels)
+	}
+	return &Collector{
+		source: source,
+		hits: desc("hits_total", "Number of Get which found item."),
+		misses: desc("misses_total", "Number of Get which did not find item."),
+		loads: desc("loads_total", "Number of calls of loader."),
+		loadErrors: desc("load_errors_total", "Number of errors of loader."),
+		evictions: desc("evictions_total", "Number of removed items by reason.", "reason"),
+		items: desc("items", "Current number of items."),
+		size: desc("size_bytes", "Current size of items in bytes."),
+		thresholdSize: desc("threshold_size_bytes", "ThresholdSize of cache in bytes, 0 is unlimited."),
+		optimize: desc("optimize_duration_seconds", "Duration of Optimize."),
+	}
+}
+
+// Describe send descriptors of metrics.
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.loads
+	ch <- c.loadErrors
+	ch <- c.evictions
+	ch <- c.items
+	ch <- c.size
+	ch <- c.thresholdSize
+	ch <- c.optimize
+}
+
+// Collect send current statistics of cache.
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.source.Stats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(stats.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(stats.Misses))
+	ch <- prometheus.MustNewConstMetric(c.loads, prometheus.CounterValue, float64(stats.Loads))
+	ch <- prometheus.MustNewConstMetric(c.loadErrors, prometheus.CounterValue, float64(stats.LoadErrors))
+	for _, reason := range cache.EvictionReasons {
+		ch <- prometheus.MustNewConstMetric(c.evictions, prometheus.CounterValue, float64(stats.Evictions[reason]), reason.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.items, prometheus.GaugeValue, float64(stats.Count))
+	ch <- prometheus.MustNewConstMetric(c.size, prometheus.GaugeValue, float64(stats.Size))
+	ch <- prometheus.MustNewConstMetric(c.thresholdSize, prometheus.GaugeValue, float64(stats.ThresholdSize))
+	ch <- prometheus.MustNewConstSummary(c.optimize, uint64(stats.Optimizations), stats.OptimizeDuration.Seconds(), nil)
+}
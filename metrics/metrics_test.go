@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	cache "github.com/tico8/go-cache"
+)
+
+// gather gather metrics by name and value of "name" label.
+func gather(t *testing.T, registry *prometheus.Registry) map[string]map[string][]*dto.Metric {
+	t.Helper()
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	result := map[string]map[string][]*dto.Metric{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() != "name" {
+					continue
+				}
+				if result[family.GetName()] == nil {
+					result[family.GetName()] = map[string][]*dto.Metric{}
+				}
+				result[family.GetName()][label.GetValue()] = append(result[family.GetName()][label.GetValue()], metric)
+			}
+		}
+	}
+	return result
+}
+
+func TestOK_Collector(t *testing.T) {
+	// enable logger
+	cache.EnableLogger(true)
+
+	c1 := cache.New(cache.Option{ThresholdSize: 1024})
+	c2 := cache.NewTyped[int, int](cache.TypedOption[int, int]{})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(NewCollector("c1", c1), NewCollector("c2", c2))
+
+	c1.Set("key1", "value", time.Duration(10) * time.Second)
+	c1.Set("key2", "value", time.Duration(10) * time.Second)
+	c1.Get("key1")
+	c1.Get("key3")
+	c1.Del("key2")
+	c1.Optimize()
+	c2.Get(1)
+
+	metrics := gather(t, registry)
+	if v := metrics["cache_hits_total"]["c1"][0].GetCounter().GetValue(); v != 1 {
+		t.Errorf("hits(%v) is invalid.", v)
+	}
+	if v := metrics["cache_misses_total"]["c2"][0].GetCounter().GetValue(); v != 1 {
+		t.Errorf("misses(%v) is invalid.", v)
+	}
+	if v := metrics["cache_items"]["c1"][0].GetGauge().GetValue(); v != 1 {
+		t.Errorf("items(%v) is invalid.", v)
+	}
+	if v := metrics["cache_size_bytes"]["c1"][0].GetGauge().GetValue(); v != float64(c1.Size()) {
+		t.Errorf("size(%v) is invalid.", v)
+	}
+	if v := metrics["cache_threshold_size_bytes"]["c1"][0].GetGauge().GetValue(); v != 1024 {
+		t.Errorf("threshold size(%v) is invalid.", v)
+	}
+	if v := metrics["cache_optimize_duration_seconds"]["c1"][0].GetSummary().GetSampleCount(); v != 1 {
+		t.Errorf("optimize count(%v) is invalid.", v)
+	}
+
+	evictions := metrics["cache_evictions_total"]["c1"]
+	if len(evictions) != len(cache.EvictionReasons) {
+		t.Errorf("evictions(%d) is invalid.", len(evictions))
+	}
+	for _, metric := range evictions {
+		for _, label := range metric.GetLabel() {
+			if label.GetName() == "reason" && label.GetValue() == cache.ReasonDeleted.String() && metric.GetCounter().GetValue() != 1 {
+				t.Errorf("deleted(%v) is invalid.", metric.GetCounter().GetValue())
+			}
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// snapshotVersion version of format of snapshot
+	snapshotVersion = 2
+)
+
+// snapshotHeader header of snapshot, followed by Count records.
+// If Encrypted is true, each record is encoded and encrypted, and written as bytes.
+type snapshotHeader struct {
+	Version int
+	Count int
+	Encrypted bool
+}
+
+// itemRecord item of cache in snapshot.
+// Value is encoded by Codec of option.
+type itemRecord[K comparable] struct {
+	Key K
+	Value []byte
+	Expiration time.Time
+	TTL time.Duration
+	AccessCount int64
+	LastAccess time.Time
+}
+
+// SaveFile save items to file.
+// File is written to temporary file and renamed, so that it is replaced atomically.
+// param path - path of file
+// return arg1 - Error
+func (c *cache[K, V]) SaveFile(path string) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path) + ".tmp*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+
+	w := bufio.NewWriter(tmp)
+	if err = c.Save(w); err != nil {
+		return err
+	}
+	if err = w.Flush(); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadFile load items from file saved by SaveFile.
+// param path - path of file
+// return arg1 - Error
+func (c *cache[K, V]) LoadFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return c.Load(bufio.NewReader(f))
+}
+
+// Save save items to writer.
+// Items of each shard are copied under read lock, and encoded without lock.
+// param w - writer
+// return arg1 - Error
+func (c *cache[K, V]) Save(w io.Writer) error {
+	items := c.snapshot()
+	keys := c.option.KeyProvider
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(snapshotHeader{Version: snapshotVersion, Count: len(items), Encrypted: keys != nil}); err != nil {
+		return err
+	}
+	for _, item := range items {
+		record, err := c.newItemRecord(item)
+		if err != nil {
+			return fmt.Errorf("encode key = %v: %w", item.Key, err)
+		}
+		if keys == nil {
+			err = enc.Encode(&record)
+		} else {
+			var data []byte
+			if data, err = encryptRecord(keys, &record); err == nil {
+				err = enc.Encode(data)
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("encode key = %v: %w", item.Key, err)
+		}
+	}
+	Debug("saved items. count = %d", len(items))
+	return nil
+}
+
+// Load load items from reader saved by Save.
+// Items which are expired are skipped. Loaded items are not written to Writer.
+// param r - reader
+// return arg1 - Error
+func (c *cache[K, V]) Load(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+	var header snapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+	if header.Version != snapshotVersion {
+		return fmt.Errorf("version of snapshot is not supported. version = %d", header.Version)
+	}
+
+	loaded := 0
+	for i := 0; i < header.Count; i++ {
+		var record itemRecord[K]
+		if !header.Encrypted {
+			if err := dec.Decode(&record); err != nil {
+				return err
+			}
+		} else {
+			var data []byte
+			if err := dec.Decode(&data); err != nil {
+				return err
+			}
+			if err := decryptRecord(c.option.KeyProvider, data, &record); err != nil {
+				return err
+			}
+		}
+		if ok, err := c.restore(record); err != nil {
+			return err
+		} else if ok {
+			loaded++
+		}
+	}
+	Debug("loaded items. count = %d skipped = %d", loaded, header.Count - loaded)
+	return nil
+}
+
+// snapshot copy items of all shards.
+// return arg1 - items
+func (c *cache[K, V]) snapshot() []*TypedItem[K, V] {
+	var items []*TypedItem[K, V]
+	for _, s := range c.shards {
+		s.RLock()
+		for _, item := range s.items {
+			items = append(items, item)
+		}
+		s.RUnlock()
+	}
+	return items
+}
+
+// restore set item of record to cache.
+// param record - record of item
+// return arg1 - If item is set, return true. Expired item is not set.
+// return arg2 - Error
+func (c *cache[K, V]) restore(record itemRecord[K]) (bool, error) {
+	item, err := c.recordItem(record)
+	if err != nil {
+		return false, fmt.Errorf("decode key = %v: %w", record.Key, err)
+	}
+	if item.Expired(time.Now().Add(-c.option.stalePeriod())) {
+		return false, nil
+	}
+	if err := c.check(item.Object); err != nil {
+		return false, err
+	}
+	if err := c.encode(item); err != nil {
+		return false, fmt.Errorf("encode key = %v: %w", item.Key, err)
+	}
+	item.size = c.SizeOfItem(item)
+	c.store(item)
+	return true, nil
+}
+
+// newItemRecord create record of item, whose value is encoded.
+// param item - item
+// return arg1 - record of item
+// return arg2 - Error
+func (c *cache[K, V]) newItemRecord(item *TypedItem[K, V]) (itemRecord[K], error) {
+	value, err := c.encodedValue(item)
+	if err != nil {
+		return itemRecord[K]{}, err
+	}
+	record := itemRecord[K]{
+		Key: item.Key,
+		Value: value,
+		TTL: item.ttl,
+		AccessCount: atomic.LoadInt64(&item.AccessCount),
+	}
+	if item.Expiration != nil {
+		record.Expiration = *item.Expiration
+	}
+	if lastAccess := item.LastAccess.Load(); lastAccess != nil {
+		record.LastAccess = *lastAccess
+	}
+	return record, nil
+}
+
+// recordItem create item of record, whose value is decoded and size is not set.
+// param r - record of item
+// return arg1 - item
+// return arg2 - Error
+func (c *cache[K, V]) recordItem(r itemRecord[K]) (*TypedItem[K, V], error) {
+	value, err := c.unmarshal(r.Value)
+	if err != nil {
+		return nil, err
+	}
+	expiration := r.Expiration
+	item := &TypedItem[K, V]{
+		Key: r.Key,
+		Object: value,
+		Expiration: &expiration,
+		AccessCount: r.AccessCount,
+		ttl: r.TTL,
+	}
+	if !r.LastAccess.IsZero() {
+		lastAccess := r.LastAccess
+		item.LastAccess.Store(&lastAccess)
+	}
+	return item, nil
+}
+
+// encryptRecord encode record by gob, and encrypt it.
+// param keys - provider of keys
+// param record - pointer to record
+// return arg1 - encrypted record
+// return arg2 - Error
+func encryptRecord(keys KeyProvider, record interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(record); err != nil {
+		return nil, err
+	}
+	return encrypt(keys, buf.Bytes())
+}
+
+// decryptRecord decrypt record, and decode it by gob.
+// param keys - provider of keys
+// param data - encrypted record
+// param record - pointer to record
+// return arg1 - Error
+func decryptRecord(keys KeyProvider, data []byte, record interface{}) error {
+	data, err := decrypt(keys, data)
+	if err != nil {
+		return err
+	}
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(record)
+}
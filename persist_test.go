@@ -0,0 +1,106 @@
+package cache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type persistValue struct {
+	Name string
+	Count int
+}
+
+func TestOK_SaveFile(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", "expired", time.Duration(20) * time.Millisecond)
+	c.Get("key1")
+	c.Get("key1")
+
+	path := filepath.Join(t.TempDir(), "cache.dat")
+	if err := c.SaveFile(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	time.Sleep(time.Duration(30) * time.Millisecond)
+
+	r := New(Option{})
+	if err := r.LoadFile(path); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if v, found := r.Get("key2"); !found || *v != 2 {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+	if _, found := r.GetItem("key3"); found {
+		t.Errorf("expired item is loaded.")
+	}
+	item, _ := r.GetItem("key1")
+	original, _ := c.GetItem("key1")
+	if item.Object != "value" || !item.Expiration.Equal(*original.Expiration) || item.AccessCount != 2 || !item.LastAccess.Load().Equal(*original.LastAccess.Load()) {
+		t.Errorf("item(%+v) is invalid.", item)
+	}
+	if r.Size() != r.SizeOfItem(item) + r.SizeOfItem(r.GetItems()["key2"]) {
+		t.Errorf("size(%d) is invalid.", r.Size())
+	}
+
+	// no temporary file is left
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("files(%d) are invalid.", len(files))
+	}
+}
+
+func TestOK_Save_Typed(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[int, persistValue](TypedOption[int, persistValue]{})
+	c.Set(1, persistValue{Name: "tico8", Count: 1}, time.Duration(10) * time.Second)
+
+	var buf bytes.Buffer
+	if err := c.Save(&buf); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	r := NewTyped[int, persistValue](TypedOption[int, persistValue]{Shards: 4})
+	if err := r.Load(&buf); err != nil {
+		t.Fatalf("error = %v", err)
+	}
+	if v, found := r.Get(1); !found || v.Name != "tico8" {
+		t.Errorf("v(%v) is invalid.", v)
+	}
+}
+
+func TestNG_LoadFile(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	if err := c.LoadFile(filepath.Join(t.TempDir(), "none.dat")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if err := c.Load(bytes.NewBufferString("broken")); err == nil {
+		t.Errorf("error is not returned.")
+	}
+
+	// failed save does not replace file
+	path := filepath.Join(t.TempDir(), "cache.dat")
+	os.WriteFile(path, []byte("old"), 0644)
+	// channel can not be encoded
+	c.shards[0].store(&Item{Key: "key", Object: make(chan int), Expiration: &time.Time{}})
+	if err := c.SaveFile(path); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if b, _ := os.ReadFile(path); string(b) != "old" {
+		t.Errorf("file is replaced.")
+	}
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("files(%d) are invalid.", len(files))
+	}
+}
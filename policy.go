@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// EvictionMode mode of eviction
+type EvictionMode int
+
+const (
+	// EvictPriority evict items of low priority (default)
+	EvictPriority EvictionMode = iota
+	// EvictLRU evict least recently used items on Set
+	EvictLRU
+	// EvictLFU evict least frequently used items on Set
+	EvictLFU
+	// EvictTinyLFU admit and evict items by estimated frequency in the style of W-TinyLFU on Set
+	EvictTinyLFU
+	// EvictARC evict items by Adaptive Replacement Cache on Set
+	EvictARC
+	// EvictSLRU evict items by segmented LRU, which protects items hit twice from scan, on Set
+	EvictSLRU
+)
+
+// EvictionPolicy policy decides which items are evicted from cache.
+// Calls of policy are serialized by cache.
+// AccessCount and LastAccess of item may be updated concurrently, so they must be read atomically.
+type EvictionPolicy[K comparable, V any] interface {
+	// Insert is called when item is set to cache.
+	// param item - item of cache
+	Insert(item *TypedItem[K, V])
+
+	// Access is called when item is got from cache.
+	// param item - item of cache
+	Access(item *TypedItem[K, V])
+
+	// Delete is called when item is deleted from cache.
+	// param item - item of cache
+	Delete(item *TypedItem[K, V])
+
+	// Compact is called by Optimize.
+	// Expired items are deleted by Optimize, even if they are not in drop.
+	// param items - items of cache
+	// return arg1 - items to be deleted regardless of the size of cache
+	// return arg2 - other items, in order of eviction when the size of cache is greater than ThresholdSize
+	Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V])
+}
+
+// Evictor is EvictionPolicy which evicts items on Set.
+// If policy implements Evictor, items are evicted as soon as the size of cache is greater than ThresholdSize.
+type Evictor[K comparable, V any] interface {
+	// Victim get item to be evicted next.
+	// return arg1 - item to be evicted
+	// return arg2 - If there is no item, return false.
+	Victim() (item *TypedItem[K, V], found bool)
+}
+
+// Ager is EvictionPolicy which ages information of items.
+// Age is called by Optimize.
+type Ager interface {
+	// Age age information of items.
+	// param now - current time
+	Age(now time.Time)
+}
+
+// newPolicy create eviction policy from option.
+// param opt - option
+// return arg1 - instance of EvictionPolicy
+func newPolicy[K comparable, V any](opt *TypedOption[K, V]) EvictionPolicy[K, V] {
+	if opt.Policy != nil {
+		return opt.Policy()
+	}
+	switch opt.Eviction {
+	case EvictLRU:
+		return newLRUPolicy[K, V]()
+	case EvictLFU:
+		return newLFUPolicy[K, V](opt)
+	case EvictTinyLFU:
+		return newTinyLFUPolicy[K, V](opt)
+	case EvictARC:
+		return newARCPolicy[K, V](opt)
+	case EvictSLRU:
+		return newSLRUPolicy[K, V](opt)
+	default:
+		return &priorityPolicy[K, V]{option: opt}
+	}
+}
+
+// priorityPolicy is default policy.
+// priority of item is +1 for each of ...
+//  - no expiration
+//  - not expired
+//  - accessed within ThresholdAccess
+//  - accessed ThresholdAccessCount times or more
+// Items of priority 0 are deleted, and lower priority items are evicted first.
+type priorityPolicy[K comparable, V any] struct {
+	option *TypedOption[K, V]
+}
+
+func (p *priorityPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	atomic.StoreInt64(&item.Priority, int64(p.priority(item)))
+}
+
+func (p *priorityPolicy[K, V]) Access(item *TypedItem[K, V]) {
+}
+
+func (p *priorityPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+}
+
+func (p *priorityPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	// apply priority
+	tmp := make(sortableItems[K, V], 0, len(items))
+	for _, item := range items {
+		priority := p.priority(item)
+		if priority == 0 {
+			drop = append(drop, item)
+		} else {
+			// items may be read by GetItems, while shard is not write locked
+			atomic.StoreInt64(&item.Priority, int64(priority))
+			tmp = append(tmp, item)
+		}
+	}
+
+	// sort
+	sort.Sort(tmp)
+	return drop, tmp
+}
+
+func (p *priorityPolicy[K, V]) priority(item *TypedItem[K, V]) int {
+	now := time.Now()
+	priority := 0 //Items to be deleted
+	if item == nil {
+		return priority
+	}
+
+	// no expiration
+	if item.Expiration == nil || item.Expiration.IsZero() {
+		priority++
+	}
+	// expiration > now
+	if item.Expiration != nil && item.Expiration.After(now) {
+		priority++
+	}
+	// last access + threshold > now
+	lastAccess := item.LastAccess.Load()
+	if p.option.ThresholdAccess != 0 && lastAccess != nil && lastAccess.Before(now) && lastAccess.Add(p.option.ThresholdAccess).After(now) {
+		priority++
+	}
+	// access count >= threshold
+	if p.option.ThresholdAccessCount != 0 && atomic.LoadInt64(&item.AccessCount) >= p.option.ThresholdAccessCount {
+		priority++
+	}
+
+	return priority
+}
+
+type sortableItems[K comparable, V any] []*TypedItem[K, V]
+
+func (sitems sortableItems[K, V]) Len() int { return len(sitems) }
+func (sitems sortableItems[K, V]) Less(i, j int) bool {
+	ii := sitems[i]
+	ij := sitems[j]
+	return !ii.PriorityThan(ij)
+}
+func (sitems sortableItems[K, V]) Swap(i, j int) { sitems[i], sitems[j] = sitems[j], sitems[i] }
@@ -0,0 +1,86 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// fifoPolicy evict items in order of insertion.
+type fifoPolicy struct {
+	keys []string
+	accessed int
+}
+
+func (p *fifoPolicy) Insert(item *Item) {
+	p.keys = append(p.keys, item.Key)
+}
+
+func (p *fifoPolicy) Access(item *Item) {
+	p.accessed++
+}
+
+func (p *fifoPolicy) Delete(item *Item) {
+	for i, key := range p.keys {
+		if key == item.Key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			return
+		}
+	}
+}
+
+func (p *fifoPolicy) Compact(items []*Item) (drop, order []*Item) {
+	m := map[string]*Item{}
+	for _, item := range items {
+		m[item.Key] = item
+	}
+	for _, key := range p.keys {
+		order = append(order, m[key])
+	}
+	return nil, order
+}
+
+func TestOK_Policy_Custom(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	p := &fifoPolicy{}
+	opt := Option{
+			ThresholdSize: 1,
+			Policy: func() EvictionPolicy[string, interface{}] { return p },
+		}
+	c := New(opt)
+
+	c.Set("key1", "value1", time.Duration(10) * time.Second)
+	c.Set("key2", "value2", time.Duration(10) * time.Second)
+	c.Set("key1", "value3", time.Duration(10) * time.Second) // key1 is moved to last
+	_, _ = c.Get("key2")
+	if p.accessed != 1 {
+		t.Errorf("accessed(%d) is invalid. expected = %d", p.accessed, 1)
+	}
+	if len(p.keys) != 2 || p.keys[0] != "key2" || p.keys[1] != "key1" {
+		t.Errorf("keys(%v) is invalid. expected = %v", p.keys, []string{"key2", "key1"})
+	}
+
+	// only key2 is evicted to be ThresholdSize
+	c.option.ThresholdSize = c.Size() - 1
+	c.Optimize()
+	if _, found := c.GetItem("key2"); found {
+		t.Errorf("key(%v) is not evicted.", "key2")
+	}
+	if _, found := c.GetItem("key1"); !found {
+		t.Errorf("key(%v) is evicted.", "key1")
+	}
+	if len(p.keys) != 1 {
+		t.Errorf("keys(%v) is invalid. expected = %v", p.keys, []string{"key1"})
+	}
+}
+
+func TestOK_Policy_Default(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	if _, ok := c.shards[0].policy.(*priorityPolicy[string, interface{}]); !ok {
+		t.Errorf("policy(%T) is not default policy.", c.shards[0].policy)
+	}
+}
@@ -0,0 +1,103 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// run with -race to detect data races
+func TestOK_Race_Modes(t *testing.T) {
+	modes := []EvictionMode{EvictPriority, EvictLRU, EvictLFU, EvictTinyLFU, EvictARC, EvictSLRU}
+	for _, mode := range modes {
+		for _, shards := range []int{0, 4} {
+			opt := TypedOption[string, int]{
+					ThresholdSize: 4096,
+					ThresholdAccess: time.Duration(1) * time.Second,
+					ThresholdAccessCount: 2,
+					Eviction: mode,
+					Shards: shards,
+				}
+			t.Run(fmt.Sprintf("mode%d/shards%d", mode, shards), func(t *testing.T) {
+				raceCache(t, NewTyped[string, int](opt))
+			})
+		}
+	}
+}
+
+func raceCache(t *testing.T, c *TypedCache[string, int]) {
+	var wg sync.WaitGroup
+	stop := make(chan bool)
+
+	// writers
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("key%d", i % 50)
+				c.Set(key, i, time.Duration(10) * time.Millisecond)
+				if i % 7 == g {
+					c.Del(key)
+				}
+			}
+		}(g)
+	}
+	// readers of same keys
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				key := fmt.Sprintf("key%d", i % 50)
+				_, _ = c.Get(key)
+				if item, found := c.GetItem(key); found {
+					_ = item.LastAccess.Load()
+					_ = atomic.LoadInt64(&item.Priority)
+				}
+			}
+		}()
+	}
+	// introspection and optimizer
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				c.Optimize()
+				_ = c.List()
+				for _, item := range c.GetItems() {
+					_ = atomic.LoadInt64(&item.Priority)
+				}
+				_ = c.Size()
+			}
+		}
+	}()
+
+	time.Sleep(time.Duration(50) * time.Millisecond)
+	close(stop)
+	wg.Wait()
+
+	// size is consistent with items
+	size := 0
+	for _, item := range c.GetItems() {
+		size += item.size
+	}
+	if c.Size() != size {
+		t.Errorf("size(%d) is invalid. expected = %d", c.Size(), size)
+	}
+}
+
+func TestOK_Race_Optimizer(t *testing.T) {
+	c := New(Option{})
+	c.Set("key", "value", time.Duration(10) * time.Second)
+
+	c.RunOptimizer(time.Duration(1))
+	_, _ = c.Get("key")
+	c.StopOptimizer()
+}
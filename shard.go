@@ -0,0 +1,326 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// shard shard of cache
+// Items are split into shards by hash of key, and each shard is locked independently.
+// ThresholdSize in option of shard is the size for the shard.
+type shard[K comparable, V any] struct {
+	sync.RWMutex
+	items map[K]*TypedItem[K, V]
+	option *TypedOption[K, V]
+	size int
+	policy EvictionPolicy[K, V]
+	policyMu sync.Mutex // serialize Access and Compact of policy under RLock
+	evicted []eviction[K, V] // removed items, which are notified after unlock
+	notify func(evicted []eviction[K, V])
+	journal func(item *TypedItem[K, V], deleted bool) // called under the lock
+}
+
+// newShard create shard.
+// param opt - option for the shard
+// param notify - function called with removed items outside the lock
+// param journal - function called with changed item under the lock
+// return arg1 - instance of shard
+func newShard[K comparable, V any](opt *TypedOption[K, V], notify func(evicted []eviction[K, V]), journal func(item *TypedItem[K, V], deleted bool)) *shard[K, V] {
+	return &shard[K, V]{
+		items: map[K]*TypedItem[K, V]{},
+		option: opt,
+		policy: newPolicy(opt),
+		notify: notify,
+		journal: journal,
+	}
+}
+
+// unlock unlock shard, and notify items removed while it is locked.
+func (s *shard[K, V]) unlock() {
+	s.notifyEvicted(s.unlockDeferred())
+}
+
+// unlockDeferred unlock shard, and return items removed while it is locked, which should be notified by caller.
+func (s *shard[K, V]) unlockDeferred() []eviction[K, V] {
+	evicted := s.evicted
+	s.evicted = nil
+	s.Unlock()
+	return evicted
+}
+
+// notifyEvicted notify removed items.
+func (s *shard[K, V]) notifyEvicted(evicted []eviction[K, V]) {
+	if len(evicted) > 0 && s.notify != nil {
+		s.notify(evicted)
+	}
+}
+
+// store set item, and evict items over ThresholdSize.
+// param item - item of cache, whose size is set
+// return arg1 - replaced item, nil if item is newly set
+func (s *shard[K, V]) store(item *TypedItem[K, V]) (old *TypedItem[K, V]) {
+	old, evicted := s.storeDeferred(item)
+	s.notifyEvicted(evicted)
+	return old
+}
+
+// storeDeferred set item, and evict items over ThresholdSize, without notifying removed items.
+// param item - item of cache, whose size is set
+// return arg1 - replaced item, nil if item is newly set
+// return arg2 - removed items, which should be notified by caller
+func (s *shard[K, V]) storeDeferred(item *TypedItem[K, V]) (old *TypedItem[K, V], evicted []eviction[K, V]) {
+	s.Lock()
+	old = s.items[item.Key]
+	s.set(item.Key, item)
+	s.evict()
+	return old, s.unlockDeferred()
+}
+
+// replace set item only if old item is not replaced yet.
+// param old - item to be replaced
+// param item - item of cache, whose size is set
+// return arg1 - If item is set, return true.
+func (s *shard[K, V]) replace(old, item *TypedItem[K, V]) bool {
+	s.Lock()
+	defer s.unlock()
+	if s.items[item.Key] != old {
+		return false
+	}
+	s.set(item.Key, item)
+	s.evict()
+	return true
+}
+
+func (s *shard[K, V]) set(key K, item *TypedItem[K, V]) {
+	beforeItemSize := 0
+	if s.items[key] != nil {
+		beforeItem := s.items[key]
+		beforeItemSize = beforeItem.size
+		s.policy.Delete(beforeItem)
+		s.evicted = append(s.evicted, eviction[K, V]{beforeItem, ReasonReplaced})
+	}
+	s.items[key] = item
+	s.size = s.size - beforeItemSize + item.size
+	s.policy.Insert(item)
+	if s.journal != nil {
+		s.journal(item, false)
+	}
+}
+
+// evict evict items by Evictor until the size of shard is ThresholdSize or less.
+func (s *shard[K, V]) evict() {
+	evictor, ok := s.policy.(Evictor[K, V])
+	if !ok {
+		return
+	}
+	for 0 < s.option.ThresholdSize && s.size > s.option.ThresholdSize {
+		item, found := evictor.Victim()
+		if !found {
+			break
+		}
+		s.del(item.Key, ReasonCapacity)
+		Debug("eviction delete key = %v", item.Key)
+	}
+}
+
+// access get item and update access information of item.
+func (s *shard[K, V]) access(key K) (item *TypedItem[K, V], found bool) {
+	s.RLock()
+	now := time.Now()
+	item, found = s.get(key)
+	if found && s.dead(item, now) {
+		s.RUnlock()
+		s.expire(key, now)
+		return nil, false
+	}
+	if found {
+		atomic.AddInt64(&item.AccessCount, 1)
+		item.LastAccess.Store(&now)
+		s.policyMu.Lock()
+		s.policy.Access(item)
+		s.policyMu.Unlock()
+	}
+	s.RUnlock()
+	return item, found
+}
+
+// lookup get item without updating access information of item.
+func (s *shard[K, V]) lookup(key K) (item *TypedItem[K, V], found bool) {
+	s.RLock()
+	now := time.Now()
+	item, found = s.get(key)
+	if found && s.dead(item, now) {
+		s.RUnlock()
+		s.expire(key, now)
+		return nil, false
+	}
+	s.RUnlock()
+	return item, found
+}
+
+// dead check item is expired over stale period.
+// Expired item is kept as stale until StaleWhileRevalidate or StaleIfError passes.
+// param item - item of cache
+// param now - current time
+// return arg1 - If item is to be deleted, return true.
+func (s *shard[K, V]) dead(item *TypedItem[K, V], now time.Time) bool {
+	return item.Expired(now.Add(-s.option.stalePeriod()))
+}
+
+// expire delete item if it is still expired.
+// param key - key of item
+// param now - current time
+func (s *shard[K, V]) expire(key K, now time.Time) {
+	s.Lock()
+	item, found := s.get(key)
+	if found && s.dead(item, now) {
+		s.del(key, ReasonExpired)
+		Debug("expired delete key = %v", key)
+	}
+	s.unlock()
+}
+
+// private function
+func (s *shard[K, V]) get(key K) (item *TypedItem[K, V], found bool) {
+	item = s.items[key]
+	if item != nil {
+		found = true
+	}
+
+	return item, found
+}
+
+func (s *shard[K, V]) remove(key K) {
+	s.notifyEvicted(s.removeDeferred(key))
+}
+
+// removeDeferred delete item without notifying it.
+// param key - key of item
+// return arg1 - removed items, which should be notified by caller
+func (s *shard[K, V]) removeDeferred(key K) []eviction[K, V] {
+	s.Lock()
+	s.del(key, ReasonDeleted)
+	return s.unlockDeferred()
+}
+
+// del delete item, and record it to be notified.
+// param key - key of item
+// param reason - reason of deletion
+func (s *shard[K, V]) del(key K, reason EvictionReason) {
+	item, found := s.get(key)
+	size := 0
+	if found {
+		size = item.size
+		s.policy.Delete(item)
+		s.evicted = append(s.evicted, eviction[K, V]{item, reason})
+		if s.journal != nil {
+			s.journal(item, true)
+		}
+	}
+	delete(s.items, key)
+	//shard size
+	s.size = s.size - size
+}
+
+// list get keys of shard.
+// Items expired over stale period are deleted and not listed.
+func (s *shard[K, V]) list(names []K) []K {
+	s.Lock()
+	now := time.Now()
+	for key, item := range s.items {
+		if s.dead(item, now) {
+			s.del(key, ReasonExpired)
+			Debug("expired delete key = %v", key)
+			continue
+		}
+		names = append(names, key)
+	}
+	s.unlock()
+
+	return names
+}
+
+// stat get number of items and size of shard.
+func (s *shard[K, V]) stat() (count int, size int) {
+	s.RLock()
+	count, size = len(s.items), s.size
+	s.RUnlock()
+	return count, size
+}
+
+// optimize optimize shard by eviction policy.
+func (s *shard[K, V]) optimize() {
+	if ager, ok := s.policy.(Ager); ok {
+		s.Lock()
+		ager.Age(time.Now())
+		s.Unlock()
+	}
+
+	// shard is not write locked while policy decides items to be deleted
+	s.RLock()
+	s.policyMu.Lock()
+	tmp := make([]*TypedItem[K, V], 0, len(s.items))
+	for _, item := range s.items {
+		tmp = append(tmp, item)
+	}
+	drop, order := s.policy.Compact(tmp)
+	s.policyMu.Unlock()
+	s.RUnlock()
+
+	// expired items are dropped, even if policy does not drop them
+	now := time.Now()
+	dropped := make(map[*TypedItem[K, V]]bool, len(drop))
+	for _, item := range drop {
+		dropped[item] = true
+	}
+	for _, item := range tmp {
+		if s.dead(item, now) && !dropped[item] {
+			drop = append(drop, item)
+		}
+	}
+
+	// delete items of no longer needed, but stale items are kept unless the size is over
+	var stales []*TypedItem[K, V]
+	for _, item := range drop {
+		if item.Expired(now) && !s.dead(item, now) {
+			stales = append(stales, item)
+			continue
+		}
+		reason := ReasonLowPriority
+		if item.Expired(now) {
+			reason = ReasonExpired
+		}
+		s.Lock()
+		if s.delItem(item, reason) {
+			Debug("optimizing delete key = %v", item.Key)
+		}
+		s.unlock()
+	}
+
+	// compaction, stale items are evicted first
+	order = append(stales, order...)
+	for _, item := range order {
+		s.Lock()
+		if s.option.ThresholdSize <= 0 || s.size <= s.option.ThresholdSize {
+			s.Unlock()
+			break
+		}
+		if s.delItem(item, ReasonCapacity) {
+			Debug("compaction delete key = %v", item.Key)
+		}
+		s.unlock()
+	}
+}
+
+// delItem delete item if it is not replaced.
+// param item - item to be deleted
+// param reason - reason of deletion
+// return arg1 - If item is deleted, return true.
+func (s *shard[K, V]) delItem(item *TypedItem[K, V], reason EvictionReason) bool {
+	if s.items[item.Key] != item {
+		return false
+	}
+	s.del(item.Key, reason)
+	return true
+}
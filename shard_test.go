@@ -0,0 +1,194 @@
+package cache
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOK_Shard_Distribute(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Shards: 4,
+		}
+	c := NewTyped[string, int64](opt)
+
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for i := 0; i < 100; i++ {
+		v, found := c.Get(fmt.Sprintf("key%03d", i))
+		if !found || v != int64(i) {
+			t.Errorf("key(%v) is not found.", fmt.Sprintf("key%03d", i))
+		}
+	}
+
+	total := 0
+	for i, s := range c.shards {
+		count, size := s.stat()
+		if count == 0 {
+			t.Errorf("shard(%d) is empty.", i)
+		}
+		total += size
+	}
+	if c.Size() != total {
+		t.Errorf("size(%d) is invalid. expected = %d", c.Size(), total)
+	}
+	if len(c.List()) != 100 || len(c.GetItems()) != 100 {
+		t.Errorf("items(%d) is invalid. expected = %d", len(c.List()), 100)
+	}
+
+	c.Del("key000")
+	if _, found := c.Get("key000"); found {
+		t.Errorf("key(%v) is not deleted.", "key000")
+	}
+}
+
+func TestOK_Shard_Threshold(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	itemSize := NewTyped[string, int64](TypedOption[string, int64]{}).SizeOfItem(&TypedItem[string, int64]{Key: "key000"})
+	opt := TypedOption[string, int64]{
+			Shards: 4,
+			ThresholdSize: itemSize * 40,
+			Eviction: EvictLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for i, s := range c.shards {
+		if _, size := s.stat(); size > s.option.ThresholdSize {
+			t.Errorf("size(%d) of shard(%d) is greater than threshold(%d).", size, i, s.option.ThresholdSize)
+		}
+	}
+	if c.Size() > opt.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), opt.ThresholdSize)
+	}
+
+	// priority based compaction works per shard
+	opt.Eviction = EvictPriority
+	c = NewTyped[string, int64](opt)
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	c.Optimize()
+	if c.Size() > opt.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), opt.ThresholdSize)
+	}
+}
+
+func TestOK_Shard_SmallThreshold(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	// ThresholdSize is smaller than number of shards
+	opt := TypedOption[string, int64]{
+			Shards: 8,
+			ThresholdSize: 7,
+			Eviction: EvictLRU,
+		}
+	c := NewTyped[string, int64](opt)
+	for i := 0; i < 100; i++ {
+		c.Set(fmt.Sprintf("key%03d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for i, s := range c.shards {
+		if s.option.ThresholdSize < 1 {
+			t.Errorf("threshold(%d) of shard(%d) is unlimited.", s.option.ThresholdSize, i)
+		}
+	}
+	if c.Size() > opt.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), opt.ThresholdSize)
+	}
+
+	// remainder is spread into shards
+	total := 0
+	for i := 0; i < 8; i++ {
+		total += shardThreshold(100, 8, i)
+	}
+	if total != 100 {
+		t.Errorf("total(%d) is invalid. expected = %d", total, 100)
+	}
+}
+
+func TestOK_Shard_Concurrent(t *testing.T) {
+	opt := TypedOption[int, int]{
+			Shards: 8,
+		}
+	c := NewTyped[int, int](opt)
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				key := g * 1000 + i
+				c.Set(key, i, time.Duration(10) * time.Second)
+				if v, found := c.Get(key); !found || v != i {
+					t.Errorf("key(%v) is not found.", key)
+				}
+				if i % 2 == 0 {
+					c.Del(key)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if len(c.List()) != 4000 {
+		t.Errorf("items(%d) is invalid. expected = %d", len(c.List()), 4000)
+	}
+}
+
+func TestOK_Shard_OptimizeExpired(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	for _, mode := range []EvictionMode{EvictPriority, EvictLRU, EvictLFU, EvictTinyLFU, EvictARC, EvictSLRU} {
+		c := NewTyped[string, int64](TypedOption[string, int64]{Eviction: mode})
+		c.Set("short", 1, time.Duration(10) * time.Millisecond)
+		c.Set("long", 2, time.Duration(10) * time.Second)
+		time.Sleep(time.Duration(20) * time.Millisecond)
+
+		c.Optimize()
+		items := c.GetItems()
+		if _, found := items["short"]; found || len(items) != 1 {
+			t.Errorf("items(%d) in mode(%v) are invalid.", len(items), mode)
+		}
+		if c.Size() != c.SizeOfItem(items["long"]) {
+			t.Errorf("size(%d) in mode(%v) is invalid.", c.Size(), mode)
+		}
+	}
+}
+
+func TestOK_Shard_OptimizeStale(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	probe := NewTyped[string, int64](TypedOption[string, int64]{})
+	probe.Set("live1", 1, time.Duration(10) * time.Second)
+	itemSize := probe.Size()
+
+	// 2 items are held
+	c := NewTyped[string, int64](TypedOption[string, int64]{
+			ThresholdSize: itemSize * 5 / 2,
+			StaleIfError: time.Hour,
+		})
+	c.Set("stale", 0, time.Duration(10) * time.Millisecond)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	c.Set("live1", 1, time.Duration(10) * time.Second)
+	c.Set("live2", 2, time.Duration(10) * time.Second)
+
+	// stale item is evicted instead of live items
+	c.Optimize()
+	items := c.GetItems()
+	if _, found := items["stale"]; found || len(items) != 2 {
+		t.Errorf("items(%v) are invalid.", items)
+	}
+}
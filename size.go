@@ -0,0 +1,161 @@
+package cache
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// Sizer interface of value which reports its own size.
+// SizeOf uses Size instead of walking the value.
+type Sizer interface {
+	// Size get size of value.
+	// return arg1 - size of value in bytes
+	Size() int
+}
+
+var (
+	sizerType = reflect.TypeFor[Sizer]()
+	timeType = reflect.TypeFor[time.Time]()
+)
+
+// itemMetaSize size of metadata of item, which is priority, access count, expiration and last access.
+const itemMetaSize = int(unsafe.Sizeof(int(0)) + unsafe.Sizeof(int64(0)) + 2 * unsafe.Sizeof(time.Time{}))
+
+// visit pointer which is already counted
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// sizer walk value recursively, and count each pointer only once.
+type sizer struct {
+	visited map[visit]bool
+}
+
+// sizeOf get size of value.
+// Size is the sum of the contents: bytes of string, elements of slice and array, keys and values of map,
+// fields of struct, and values pointed by pointer and interface. Headers of string, slice and map are not counted.
+// param v - value
+// return arg1 - size of value in bytes
+func (s *sizer) sizeOf(v reflect.Value) int {
+	if !v.IsValid() {
+		return 0
+	}
+	t := v.Type()
+	if size, ok := s.sizeBySizer(v); ok {
+		return size
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return v.Len()
+	case reflect.Ptr:
+		if v.IsNil() || s.seen(v.Pointer(), t) {
+			return 0
+		}
+		return s.sizeOf(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return s.sizeOf(v.Elem())
+	case reflect.Slice:
+		if v.IsNil() || s.seen(v.Pointer(), t) {
+			return 0
+		}
+		return s.sizeOfElems(v)
+	case reflect.Array:
+		return s.sizeOfElems(v)
+	case reflect.Map:
+		if v.IsNil() || s.seen(v.Pointer(), t) {
+			return 0
+		}
+		size := 0
+		iter := v.MapRange()
+		for iter.Next() {
+			size += s.sizeOf(iter.Key()) + s.sizeOf(iter.Value())
+		}
+		return size
+	case reflect.Struct:
+		if t == timeType || flat(t) {
+			// Location of time is shared, and not counted
+			return int(t.Size())
+		}
+		size := 0
+		for i := 0; i < v.NumField(); i++ {
+			size += s.sizeOf(v.Field(i))
+		}
+		return size
+	default:
+		return int(t.Size())
+	}
+}
+
+// sizeBySizer get size by Sizer implemented by value or pointer to value.
+func (s *sizer) sizeBySizer(v reflect.Value) (int, bool) {
+	if !v.CanInterface() {
+		return 0, false
+	}
+	t := v.Type()
+	if t.Implements(sizerType) {
+		if (t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface) && v.IsNil() {
+			return 0, true
+		}
+		return v.Interface().(Sizer).Size(), true
+	}
+	if v.CanAddr() && reflect.PointerTo(t).Implements(sizerType) {
+		return v.Addr().Interface().(Sizer).Size(), true
+	}
+	return 0, false
+}
+
+// sizeOfElems get size of elements of slice or array.
+func (s *sizer) sizeOfElems(v reflect.Value) int {
+	elem := v.Type().Elem()
+	if flat(elem) && !reflect.PointerTo(elem).Implements(sizerType) {
+		return int(elem.Size()) * v.Len()
+	}
+	size := 0
+	for i := 0; i < v.Len(); i++ {
+		size += s.sizeOf(v.Index(i))
+	}
+	return size
+}
+
+// seen check whether pointer is already counted, and mark it.
+func (s *sizer) seen(ptr uintptr, t reflect.Type) bool {
+	key := visit{ptr, t}
+	if s.visited[key] {
+		return true
+	}
+	if s.visited == nil {
+		s.visited = map[visit]bool{}
+	}
+	s.visited[key] = true
+	return false
+}
+
+// flat check whether type has no pointers, so that its size is the size of type.
+func flat(t reflect.Type) bool {
+	if t.Implements(sizerType) {
+		return false
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return true
+	case reflect.Array:
+		return flat(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if !flat(t.Field(i).Type) {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"container/list"
+)
+
+const (
+	slruProtectedPercent = 80 // size of protected segment in ThresholdSize
+	slruPromoteHits = 2 // hits to be promoted to protected segment
+)
+
+// slruPolicy evict items by segmented LRU.
+// New items enter probation segment, and they are promoted to protected segment after slruPromoteHits hits.
+// Victims are chosen from probation segment first,
+// so that one-off scan of keys does not evict items in protected segment.
+type slruPolicy[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	probation *list.List // *slruEntry, front is most recently used
+	protected *list.List // *slruEntry, front is most recently used
+	protectedSize int
+	elements map[K]*list.Element
+}
+
+type slruEntry[K comparable, V any] struct {
+	item *TypedItem[K, V]
+	hits int
+	protected bool
+}
+
+func newSLRUPolicy[K comparable, V any](opt *TypedOption[K, V]) *slruPolicy[K, V] {
+	return &slruPolicy[K, V]{
+		option: opt,
+		probation: list.New(),
+		protected: list.New(),
+		elements: map[K]*list.Element{},
+	}
+}
+
+func (p *slruPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	p.elements[item.Key] = p.probation.PushFront(&slruEntry[K, V]{item: item})
+}
+
+func (p *slruPolicy[K, V]) Access(item *TypedItem[K, V]) {
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*slruEntry[K, V])
+	if entry.protected {
+		p.protected.MoveToFront(e)
+		return
+	}
+	entry.hits++
+	if entry.hits < slruPromoteHits {
+		p.probation.MoveToFront(e)
+		return
+	}
+
+	// promote to protected segment, and demote overflowed items to probation segment
+	p.probation.Remove(e)
+	entry.protected = true
+	p.elements[item.Key] = p.protected.PushFront(entry)
+	p.protectedSize += item.size
+	limit := p.option.ThresholdSize * slruProtectedPercent / 100
+	for p.protectedSize > limit && p.protected.Len() > 1 {
+		back := p.protected.Back()
+		demoted := p.protected.Remove(back).(*slruEntry[K, V])
+		demoted.protected = false
+		demoted.hits = 0
+		p.protectedSize -= demoted.item.size
+		p.elements[demoted.item.Key] = p.probation.PushFront(demoted)
+	}
+}
+
+func (p *slruPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*slruEntry[K, V])
+	if entry.protected {
+		p.protected.Remove(e)
+		p.protectedSize -= item.size
+	} else {
+		p.probation.Remove(e)
+	}
+	delete(p.elements, item.Key)
+}
+
+func (p *slruPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	order = make([]*TypedItem[K, V], 0, len(p.elements))
+	for _, l := range []*list.List{p.probation, p.protected} {
+		for e := l.Back(); e != nil; e = e.Prev() {
+			order = append(order, e.Value.(*slruEntry[K, V]).item)
+		}
+	}
+	return nil, order
+}
+
+func (p *slruPolicy[K, V]) Victim() (item *TypedItem[K, V], found bool) {
+	e := p.probation.Back()
+	if e == nil {
+		e = p.protected.Back()
+	}
+	if e == nil {
+		return nil, false
+	}
+	return e.Value.(*slruEntry[K, V]).item, true
+}
@@ -0,0 +1,89 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOK_SLRU_ScanResistance(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictSLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size() * 4 + c.Size() / 2
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+	for i := 0; i < 2; i++ {
+		_, _ = c.Get("key1")
+		_, _ = c.Get("key2")
+	}
+
+	// one-off scan does not promote items
+	for _, key := range c.List() {
+		_, _ = c.Get(key)
+	}
+	p := c.shards[0].policy.(*slruPolicy[string, int64])
+	if p.protected.Len() != 2 {
+		t.Errorf("protected(%d) is invalid. expected = %d", p.protected.Len(), 2)
+	}
+
+	// new items evict items in probation
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("scan%d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for _, key := range []string{"key1", "key2"} {
+		if _, found := c.GetItem(key); !found {
+			t.Errorf("key(%v) is evicted.", key)
+		}
+	}
+	for _, key := range []string{"key3", "key4"} {
+		if _, found := c.GetItem(key); found {
+			t.Errorf("key(%v) is not evicted.", key)
+		}
+	}
+	if c.Size() > c.option.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), c.option.ThresholdSize)
+	}
+}
+
+func TestOK_SLRU_Demote(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictSLRU,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.option.ThresholdSize = c.Size() * 3 // protected segment is for 2 items
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	for _, key := range []string{"key1", "key2", "key3"} {
+		_, _ = c.Get(key)
+		_, _ = c.Get(key)
+	}
+
+	// key1 is demoted to probation, and evicted first
+	p := c.shards[0].policy.(*slruPolicy[string, int64])
+	if p.protected.Len() != 2 {
+		t.Errorf("protected(%d) is invalid. expected = %d", p.protected.Len(), 2)
+	}
+	victim, _ := p.Victim()
+	if victim.Key != "key1" {
+		t.Errorf("victim(%v) is invalid. expected = %v", victim.Key, "key1")
+	}
+
+	c.Del("key2")
+	c.Del("key3")
+	if p.protected.Len() != 0 || p.protectedSize != 0 {
+		t.Errorf("protected(%d) is not empty.", p.protected.Len())
+	}
+}
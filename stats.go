@@ -0,0 +1,72 @@
+package cache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats statistics of cache
+type Stats struct {
+	Hits int64 // number of Get which found item, including stale item
+	Misses int64 // number of Get which did not find item
+	Loads int64 // number of keys loaded by loader, keys loaded by LoadAll are counted one by one
+	LoadErrors int64 // number of keys failed to be loaded by loader
+	Evictions map[EvictionReason]int64 // number of removed items by reason
+	Expirations int64 // number of expired items, same as Evictions[ReasonExpired]
+	Count int // current number of items
+	Size int // current size of items in bytes
+	ThresholdSize int // ThresholdSize of option
+	HitRatio float64 // Hits / (Hits + Misses), 0 if there is no Get
+	Optimizations int64 // number of Optimize
+	OptimizeDuration time.Duration // total duration of Optimize
+}
+
+// statsCounter counters of Stats, which are updated atomically.
+type statsCounter struct {
+	hits atomic.Int64
+	misses atomic.Int64
+	loads atomic.Int64
+	loadErrors atomic.Int64
+	evictions [len(reasonNames)]atomic.Int64
+	optimizations atomic.Int64
+	optimizeNanos atomic.Int64
+}
+
+// Stats get statistics of cache.
+// Counters are read without lock, so they may not be consistent with each other under concurrent use.
+// return arg1 - statistics
+func (c *cache[K, V]) Stats() Stats {
+	stats := Stats{
+		Hits: c.stats.hits.Load(),
+		Misses: c.stats.misses.Load(),
+		Loads: c.stats.loads.Load(),
+		LoadErrors: c.stats.loadErrors.Load(),
+		Evictions: make(map[EvictionReason]int64, len(EvictionReasons)),
+		ThresholdSize: c.option.ThresholdSize,
+		Optimizations: c.stats.optimizations.Load(),
+		OptimizeDuration: time.Duration(c.stats.optimizeNanos.Load()),
+	}
+	for _, reason := range EvictionReasons {
+		stats.Evictions[reason] = c.stats.evictions[reason].Load()
+	}
+	stats.Expirations = stats.Evictions[ReasonExpired]
+	stats.Count, stats.Size = c.stat()
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
+
+// ResetStats reset counters of statistics to 0.
+// Count, Size and ThresholdSize are not reset, as they are current state of cache.
+func (c *cache[K, V]) ResetStats() {
+	c.stats.hits.Store(0)
+	c.stats.misses.Store(0)
+	c.stats.loads.Store(0)
+	c.stats.loadErrors.Store(0)
+	for i := range c.stats.evictions {
+		c.stats.evictions[i].Store(0)
+	}
+	c.stats.optimizations.Store(0)
+	c.stats.optimizeNanos.Store(0)
+}
@@ -0,0 +1,77 @@
+package cache
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestOK_Stats(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	c.Set("key2", "value", time.Duration(10) * time.Millisecond)
+	c.Set("key3", "value", time.Duration(10) * time.Second)
+	c.Set("key3", "value", time.Duration(10) * time.Second)
+	c.Del("key3")
+
+	c.Get("key1")
+	c.Get("key1")
+	c.Get("key3")
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	c.Get("key2")
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 2 || stats.HitRatio != 0.5 {
+		t.Errorf("hits(%d) misses(%d) ratio(%v) are invalid.", stats.Hits, stats.Misses, stats.HitRatio)
+	}
+	if stats.Expirations != 1 || stats.Evictions[ReasonDeleted] != 1 || stats.Evictions[ReasonReplaced] != 1 {
+		t.Errorf("evictions(%v) are invalid.", stats.Evictions)
+	}
+	if stats.Count != 1 || stats.Size != c.Size() {
+		t.Errorf("count(%d) size(%d) are invalid.", stats.Count, stats.Size)
+	}
+
+	c.Optimize()
+	if stats = c.Stats(); stats.Optimizations != 1 || stats.OptimizeDuration <= 0 {
+		t.Errorf("optimizations(%d) duration(%v) are invalid.", stats.Optimizations, stats.OptimizeDuration)
+	}
+
+	c.ResetStats()
+	stats = c.Stats()
+	if stats.Hits != 0 || stats.Misses != 0 || stats.HitRatio != 0 || stats.Evictions[ReasonDeleted] != 0 || stats.Optimizations != 0 || stats.Count != 1 {
+		t.Errorf("stats(%+v) is not reset.", stats)
+	}
+}
+
+func TestOK_Stats_Load(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[string, string](TypedOption[string, string]{
+			Loader: LoaderFunc[string, string](func(key string) (string, time.Duration, error) {
+				if key == "badKey" {
+					return "", 0, errors.New("load error")
+				}
+				return "loaded", time.Duration(10) * time.Second, nil
+			}),
+	})
+
+	c.Get("key1")
+	c.Get("key1")
+	c.Get("badKey")
+	c.GetOrLoad("key2", func() (string, time.Duration, error) {
+		return "loaded", time.Duration(10) * time.Second, nil
+	})
+	c.GetAll([]string{"key1", "key3", "key4"})
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 5 {
+		t.Errorf("hits(%d) misses(%d) are invalid.", stats.Hits, stats.Misses)
+	}
+	if stats.Loads != 5 || stats.LoadErrors != 1 {
+		t.Errorf("loads(%d) loadErrors(%d) are invalid.", stats.Loads, stats.LoadErrors)
+	}
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"container/list"
+	"hash/maphash"
+)
+
+const (
+	tinyLFUWindowPercent = 1 // size of window LRU in ThresholdSize
+	tinyLFUProtectedPercent = 80 // size of protected segment in main region
+	sketchDepth = 4
+	sketchMinWidth = 64
+	sketchMaxCount = 15
+	sketchSampleFactor = 10 // counters are halved after width * sketchSampleFactor additions
+)
+
+// segment of tinyLFUPolicy
+const (
+	segmentWindow = iota
+	segmentProbation
+	segmentProtected
+)
+
+// tinyLFUPolicy admit and evict items in the style of W-TinyLFU.
+// New items enter small window LRU. Items overflowed from window are candidates of main region,
+// and candidate is admitted only if its estimated frequency is higher than the victim of main region.
+// Main region is segmented LRU, item in probation segment is promoted to protected segment when it is accessed.
+// Frequency is estimated by count-min sketch, which is halved periodically so that old frequency ages out.
+type tinyLFUPolicy[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	sketch *cmSketch[K]
+	segments [3]*list.List // *tinyLFUEntry, front is most recently used
+	sizes [3]int
+	elements map[K]*list.Element
+}
+
+type tinyLFUEntry[K comparable, V any] struct {
+	item *TypedItem[K, V]
+	segment int
+}
+
+func newTinyLFUPolicy[K comparable, V any](opt *TypedOption[K, V]) *tinyLFUPolicy[K, V] {
+	p := &tinyLFUPolicy[K, V]{
+		option: opt,
+		sketch: newCMSketch[K](sketchMinWidth),
+		elements: map[K]*list.Element{},
+	}
+	for i := range p.segments {
+		p.segments[i] = list.New()
+	}
+	return p
+}
+
+func (p *tinyLFUPolicy[K, V]) Insert(item *TypedItem[K, V]) {
+	p.sketch.ensure(len(p.elements) + 1)
+	p.sketch.increment(item.Key)
+	p.push(&tinyLFUEntry[K, V]{item: item, segment: segmentWindow})
+
+	// main region has room, so overflowed items are admitted without contest
+	if p.total() <= p.option.ThresholdSize || p.option.ThresholdSize <= 0 {
+		for p.sizes[segmentWindow] > p.windowSize() && p.segments[segmentWindow].Len() > 1 {
+			p.move(p.segments[segmentWindow].Back(), segmentProbation)
+		}
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) Access(item *TypedItem[K, V]) {
+	p.sketch.increment(item.Key)
+	e, found := p.elements[item.Key]
+	if !found {
+		return
+	}
+	entry := e.Value.(*tinyLFUEntry[K, V])
+	if entry.segment != segmentProbation {
+		p.segments[entry.segment].MoveToFront(e)
+		return
+	}
+
+	// promote to protected segment, and demote overflowed items to probation segment
+	p.move(e, segmentProtected)
+	for p.sizes[segmentProtected] > p.protectedSize() && p.segments[segmentProtected].Len() > 1 {
+		p.move(p.segments[segmentProtected].Back(), segmentProbation)
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) Delete(item *TypedItem[K, V]) {
+	if e, found := p.elements[item.Key]; found {
+		p.remove(e)
+		delete(p.elements, item.Key)
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) Compact(items []*TypedItem[K, V]) (drop, order []*TypedItem[K, V]) {
+	order = make([]*TypedItem[K, V], 0, len(p.elements))
+	for _, segment := range []int{segmentProbation, segmentWindow, segmentProtected} {
+		for e := p.segments[segment].Back(); e != nil; e = e.Prev() {
+			order = append(order, e.Value.(*tinyLFUEntry[K, V]).item)
+		}
+	}
+	return nil, order
+}
+
+// Victim get item to be evicted.
+// If window is overflowed, the candidate from window competes with the victim of main region by frequency.
+func (p *tinyLFUPolicy[K, V]) Victim() (item *TypedItem[K, V], found bool) {
+	for {
+		victim := p.segments[segmentProbation].Back()
+		if victim == nil {
+			victim = p.segments[segmentProtected].Back()
+		}
+		var candidate *list.Element
+		if p.sizes[segmentWindow] > p.windowSize() {
+			candidate = p.segments[segmentWindow].Back()
+		}
+
+		switch {
+		case candidate == nil && victim == nil:
+			if e := p.segments[segmentWindow].Back(); e != nil {
+				return e.Value.(*tinyLFUEntry[K, V]).item, true
+			}
+			return nil, false
+		case candidate == nil:
+			return victim.Value.(*tinyLFUEntry[K, V]).item, true
+		case victim == nil:
+			p.move(candidate, segmentProbation)
+			continue
+		}
+
+		candidateItem := candidate.Value.(*tinyLFUEntry[K, V]).item
+		victimItem := victim.Value.(*tinyLFUEntry[K, V]).item
+		if p.sketch.estimate(candidateItem.Key) > p.sketch.estimate(victimItem.Key) {
+			p.move(candidate, segmentProbation)
+			return victimItem, true
+		}
+		return candidateItem, true
+	}
+}
+
+func (p *tinyLFUPolicy[K, V]) windowSize() int {
+	return p.option.ThresholdSize * tinyLFUWindowPercent / 100
+}
+
+func (p *tinyLFUPolicy[K, V]) protectedSize() int {
+	return (p.option.ThresholdSize - p.windowSize()) * tinyLFUProtectedPercent / 100
+}
+
+func (p *tinyLFUPolicy[K, V]) total() int {
+	return p.sizes[segmentWindow] + p.sizes[segmentProbation] + p.sizes[segmentProtected]
+}
+
+func (p *tinyLFUPolicy[K, V]) push(entry *tinyLFUEntry[K, V]) {
+	p.elements[entry.item.Key] = p.segments[entry.segment].PushFront(entry)
+	p.sizes[entry.segment] += entry.item.size
+}
+
+func (p *tinyLFUPolicy[K, V]) remove(e *list.Element) *tinyLFUEntry[K, V] {
+	entry := e.Value.(*tinyLFUEntry[K, V])
+	p.segments[entry.segment].Remove(e)
+	p.sizes[entry.segment] -= entry.item.size
+	return entry
+}
+
+func (p *tinyLFUPolicy[K, V]) move(e *list.Element, segment int) {
+	entry := p.remove(e)
+	entry.segment = segment
+	p.push(entry)
+}
+
+// cmSketch count-min sketch to estimate frequency of keys.
+// Counters are 4-bit saturating, and halved after sample additions.
+type cmSketch[K comparable] struct {
+	seed maphash.Seed
+	rows [sketchDepth][]uint8
+	mask uint64
+	additions int
+	sample int
+}
+
+func newCMSketch[K comparable](width int) *cmSketch[K] {
+	s := &cmSketch[K]{seed: maphash.MakeSeed()}
+	s.resize(width)
+	return s
+}
+
+// ensure widen sketch for n keys.
+// Counters are kept when sketch is widened, so that frequency is not lost while cache warms up.
+// param n - number of keys
+func (s *cmSketch[K]) ensure(n int) {
+	if uint64(n) <= s.mask+1 {
+		return
+	}
+	width := int(s.mask + 1)
+	for width < n {
+		width *= 2
+	}
+	s.resize(width)
+}
+
+// resize widen rows to width, which is power of 2.
+// Index of key in wider row is its old index plus multiple of old width,
+// so counters are copied to those indexes and estimate of each key is kept.
+// param width - width of rows
+func (s *cmSketch[K]) resize(width int) {
+	for i := range s.rows {
+		row := make([]uint8, width)
+		if old := s.rows[i]; len(old) > 0 {
+			for j := range row {
+				row[j] = old[j & (len(old) - 1)]
+			}
+		}
+		s.rows[i] = row
+	}
+	s.mask = uint64(width - 1)
+	s.sample = width * sketchSampleFactor
+}
+
+func (s *cmSketch[K]) increment(key K) {
+	h1, h2 := s.hash(key)
+	for i := range s.rows {
+		idx := (h1 + uint64(i)*h2) & s.mask
+		if s.rows[i][idx] < sketchMaxCount {
+			s.rows[i][idx]++
+		}
+	}
+	s.additions++
+	if s.additions >= s.sample {
+		s.reset()
+	}
+}
+
+func (s *cmSketch[K]) estimate(key K) uint8 {
+	h1, h2 := s.hash(key)
+	min := uint8(sketchMaxCount)
+	for i := range s.rows {
+		idx := (h1 + uint64(i)*h2) & s.mask
+		if s.rows[i][idx] < min {
+			min = s.rows[i][idx]
+		}
+	}
+	return min
+}
+
+// reset halve all counters.
+func (s *cmSketch[K]) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] /= 2
+		}
+	}
+	s.additions /= 2
+	Debug("sketch is reset. width = %d", s.mask+1)
+}
+
+func (s *cmSketch[K]) hash(key K) (uint64, uint64) {
+	h := maphash.Comparable(s.seed, key)
+	return h, (h >> 32) | 1
+}
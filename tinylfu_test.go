@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOK_TinyLFU_Admission(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	itemSize := NewTyped[string, int64](TypedOption[string, int64]{}).SizeOfItem(&TypedItem[string, int64]{Key: "key1"})
+	opt := TypedOption[string, int64]{
+			Eviction: EvictTinyLFU,
+			ThresholdSize: itemSize * 4 + itemSize / 2,
+		}
+	c := NewTyped[string, int64](opt)
+	// wide sketch, so that scan keys do not collide with hot keys in every row by random seed
+	c.shards[0].policy.(*tinyLFUPolicy[string, int64]).sketch.resize(1 << 16)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	c.Set("key3", 3, time.Duration(10) * time.Second)
+	c.Set("key4", 4, time.Duration(10) * time.Second)
+	for i := 0; i < 3; i++ {
+		for _, key := range []string{"key1", "key2", "key3", "key4"} {
+			_, _ = c.Get(key)
+		}
+	}
+
+	// one-off items are not admitted
+	for i := 0; i < 10; i++ {
+		c.Set(fmt.Sprintf("scan%d", i), int64(i), time.Duration(10) * time.Second)
+	}
+	for _, key := range []string{"key1", "key2", "key3"} {
+		if _, found := c.GetItem(key); !found {
+			t.Errorf("key(%v) is evicted.", key)
+		}
+	}
+	for i := 0; i < 9; i++ {
+		if _, found := c.GetItem(fmt.Sprintf("scan%d", i)); found {
+			t.Errorf("key(%v) is admitted.", fmt.Sprintf("scan%d", i))
+		}
+	}
+	if c.Size() > c.option.ThresholdSize {
+		t.Errorf("size(%d) is greater than threshold(%d).", c.Size(), c.option.ThresholdSize)
+	}
+
+	// frequently used item is admitted
+	for i := 0; i < 6; i++ {
+		_, _ = c.Get("scan9")
+	}
+	c.Set("new", 0, time.Duration(10) * time.Second)
+	if _, found := c.GetItem("scan9"); !found {
+		t.Errorf("key(%v) is not admitted.", "scan9")
+	}
+	if len(c.List()) != 4 {
+		t.Errorf("items(%v) is invalid. expected = %d items", c.List(), 4)
+	}
+}
+
+func TestOK_TinyLFU_Del(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	opt := TypedOption[string, int64]{
+			Eviction: EvictTinyLFU,
+			ThresholdSize: 1000,
+		}
+	c := NewTyped[string, int64](opt)
+
+	c.Set("key1", 1, time.Duration(10) * time.Second)
+	c.Set("key2", 2, time.Duration(10) * time.Second)
+	_, _ = c.Get("key1")
+	c.Del("key1")
+	c.Del("key2")
+
+	p := c.shards[0].policy.(*tinyLFUPolicy[string, int64])
+	if p.total() != 0 || len(p.elements) != 0 {
+		t.Errorf("size(%d) of policy is not empty.", p.total())
+	}
+}
+
+func TestOK_CMSketch(t *testing.T) {
+	// wide sketch, so that keys do not collide in every row by random seed
+	s := newCMSketch[string](1 << 16)
+	for i := 0; i < 5; i++ {
+		s.increment("key1")
+	}
+	s.increment("key2")
+	if s.estimate("key1") < 5 {
+		t.Errorf("estimate(%d) is invalid. expected >= %d", s.estimate("key1"), 5)
+	}
+	if s.estimate("key3") > s.estimate("key1") {
+		t.Errorf("estimate(%d) of unknown key is greater than key1.", s.estimate("key3"))
+	}
+
+	// counters are halved after sample additions
+	for i := s.additions; i < s.sample; i++ {
+		s.increment("key2")
+	}
+	if s.estimate("key1") > 2 {
+		t.Errorf("estimate(%d) is not halved.", s.estimate("key1"))
+	}
+}
+
+func TestOK_CMSketch_Ensure(t *testing.T) {
+	s := newCMSketch[string](sketchMinWidth)
+	for i := 0; i < 5; i++ {
+		s.increment("key1")
+	}
+	before := s.estimate("key1")
+
+	// counters are kept when sketch is widened
+	s.ensure(sketchMinWidth * 4 + 1)
+	if int(s.mask + 1) != sketchMinWidth * 8 {
+		t.Errorf("width(%d) is invalid.", s.mask + 1)
+	}
+	if s.estimate("key1") != before {
+		t.Errorf("estimate(%d) is invalid. expected = %d", s.estimate("key1"), before)
+	}
+}
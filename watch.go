@@ -0,0 +1,227 @@
+package cache
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultWatchBuffer buffer size of channel of Watch
+	DefaultWatchBuffer = 64
+)
+
+// EventType type of event
+type EventType int
+
+const (
+	// EventSet item is set by Set, loading or reloading
+	EventSet EventType = iota
+	// EventDel item is deleted by Del
+	EventDel
+	// EventExpire item is deleted because it is expired
+	EventExpire
+	// EventEvict item is evicted by eviction policy
+	EventEvict
+)
+
+var eventNames = [...]string{"set", "del", "expire", "evict"}
+
+// String get name of event type.
+func (t EventType) String() string {
+	if t < 0 || int(t) >= len(eventNames) {
+		return "unknown"
+	}
+	return eventNames[t]
+}
+
+// OverflowPolicy policy when channel of Watch is full
+type OverflowPolicy int
+
+const (
+	// OverflowDrop drop event which does not fit in buffer (default)
+	OverflowDrop OverflowPolicy = iota
+	// OverflowBlock wait until subscriber receives event
+	OverflowBlock
+)
+
+// Event event of Cache
+type Event = TypedEvent[string, interface{}]
+
+// TypedEvent event of TypedCache
+type TypedEvent[K comparable, V any] struct {
+	Type EventType
+	Key K
+	OldValue V // value before event, zero value if item is newly set
+	NewValue V // value after event, zero value if item is removed
+}
+
+type watcher[K comparable, V any] struct {
+	prefix string
+	ch chan TypedEvent[K, V]
+	done chan bool // closed on close to release blocked sender
+	mu sync.RWMutex // held by senders, so that ch is not closed while sending
+	closed bool
+	once sync.Once
+}
+
+// send send event to watcher.
+// param event - event
+// param block - If true, wait until event is received.
+func (w *watcher[K, V]) send(event TypedEvent[K, V], block bool) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return
+	}
+	if block {
+		select {
+		case w.ch <- event:
+		case <-w.done:
+		}
+		return
+	}
+	select {
+	case w.ch <- event:
+	default:
+		Warn("event is dropped. type = %v key = %v", event.Type, event.Key)
+	}
+}
+
+func (w *watcher[K, V]) close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.mu.Lock()
+		w.closed = true
+		close(w.ch)
+		w.mu.Unlock()
+	})
+}
+
+type watchers[K comparable, V any] struct {
+	mu sync.RWMutex
+	list []*watcher[K, V]
+}
+
+// Watch subscribe events of keys which start with prefix.
+// Key which is not string is matched in the format of fmt.Sprint.
+// If subscriber is slow, events over WatchBuffer are dropped or wait by WatchOverflow.
+// param prefix - prefix of keys, "" is all keys
+// return arg1 - channel of events, which is closed by Unwatch or Close
+func (c *cache[K, V]) Watch(prefix string) <-chan TypedEvent[K, V] {
+	size := c.option.WatchBuffer
+	if size <= 0 {
+		size = DefaultWatchBuffer
+	}
+	w := &watcher[K, V]{
+		prefix: prefix,
+		ch: make(chan TypedEvent[K, V], size),
+		done: make(chan bool),
+	}
+	c.watchers.mu.Lock()
+	c.watchers.list = append(c.watchers.list, w)
+	c.watchers.mu.Unlock()
+	return w.ch
+}
+
+// Unwatch unsubscribe events, and close the channel.
+// param ch - channel returned by Watch
+func (c *cache[K, V]) Unwatch(ch <-chan TypedEvent[K, V]) {
+	var removed []*watcher[K, V]
+	c.watchers.mu.Lock()
+	list := make([]*watcher[K, V], 0, len(c.watchers.list))
+	for _, w := range c.watchers.list {
+		if w.ch == ch {
+			removed = append(removed, w)
+			continue
+		}
+		list = append(list, w)
+	}
+	c.watchers.list = list
+	c.watchers.mu.Unlock()
+
+	for _, w := range removed {
+		w.close()
+	}
+}
+
+// unwatchAll unsubscribe all events.
+func (c *cache[K, V]) unwatchAll() {
+	c.watchers.mu.Lock()
+	list := c.watchers.list
+	c.watchers.list = nil
+	c.watchers.mu.Unlock()
+
+	for _, w := range list {
+		w.close()
+	}
+}
+
+// emit send event to watchers of the key.
+// param event - event
+func (c *cache[K, V]) emit(event TypedEvent[K, V]) {
+	c.watchers.mu.RLock()
+	list := c.watchers.list
+	c.watchers.mu.RUnlock()
+	if len(list) == 0 {
+		return
+	}
+	key := keyString(event.Key)
+	for _, w := range list {
+		if strings.HasPrefix(key, w.prefix) {
+			w.send(event, c.option.WatchOverflow == OverflowBlock)
+		}
+	}
+}
+
+// emitEvicted send events of removed items.
+// Replaced items are not sent, as EventSet has the old value.
+// param evicted - removed items
+func (c *cache[K, V]) emitEvicted(evicted []eviction[K, V]) {
+	if !c.watched() {
+		return
+	}
+	for _, e := range evicted {
+		event := TypedEvent[K, V]{Key: e.item.Key, OldValue: c.value(e.item)}
+		switch e.reason {
+		case ReasonReplaced:
+			continue
+		case ReasonDeleted:
+			event.Type = EventDel
+		case ReasonExpired:
+			event.Type = EventExpire
+		default:
+			event.Type = EventEvict
+		}
+		c.emit(event)
+	}
+}
+
+// emitSet send event of item which is set.
+// param old - replaced item, nil if item is newly set
+// param item - item which is set
+func (c *cache[K, V]) emitSet(old, item *TypedItem[K, V]) {
+	if !c.watched() {
+		return
+	}
+	event := TypedEvent[K, V]{Type: EventSet, Key: item.Key, NewValue: c.value(item)}
+	if old != nil {
+		event.OldValue = c.value(old)
+	}
+	c.emit(event)
+}
+
+// watched check whether any watcher exists, so that values are not decompressed for no watcher.
+func (c *cache[K, V]) watched() bool {
+	c.watchers.mu.RLock()
+	defer c.watchers.mu.RUnlock()
+	return len(c.watchers.list) > 0
+}
+
+// keyString get key in string to match prefix.
+func keyString[K comparable](key K) string {
+	if s, ok := any(key).(string); ok {
+		return s
+	}
+	return fmt.Sprint(key)
+}
@@ -0,0 +1,145 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// receive receive event, or fail on timeout.
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-ch:
+		return event
+	case <-time.After(time.Second):
+		t.Fatalf("event is not received.")
+		return Event{}
+	}
+}
+
+func TestOK_Watch(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{})
+	ch := c.Watch("user:")
+	defer c.Unwatch(ch)
+
+	c.Set("user:1", "value1", time.Duration(10) * time.Second)
+	c.Set("item:1", "value", time.Duration(10) * time.Second) // not watched
+	c.Set("user:1", "value2", time.Duration(10) * time.Second)
+	c.Del("user:1")
+
+	expected := []Event{
+		{Type: EventSet, Key: "user:1", NewValue: "value1"},
+		{Type: EventSet, Key: "user:1", OldValue: "value1", NewValue: "value2"},
+		{Type: EventDel, Key: "user:1", OldValue: "value2"},
+	}
+	for _, e := range expected {
+		if event := receive(t, ch); event != e {
+			t.Errorf("event(%+v) is invalid. expected = %+v", event, e)
+		}
+	}
+	if len(ch) != 0 {
+		t.Errorf("unexpected events(%d).", len(ch))
+	}
+}
+
+func TestOK_Watch_ExpireAndEvict(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{Eviction: EvictLRU})
+	ch := c.Watch("")
+	defer c.Unwatch(ch)
+
+	c.Set("key1", "value", time.Duration(10) * time.Millisecond)
+	receive(t, ch)
+	time.Sleep(time.Duration(20) * time.Millisecond)
+	c.Get("key1")
+	if event := receive(t, ch); event.Type != EventExpire || event.Key != "key1" {
+		t.Errorf("event(%+v) is invalid.", event)
+	}
+
+	c.Set("key2", "value", time.Duration(10) * time.Second)
+	receive(t, ch)
+	c.option.ThresholdSize = c.Size()
+	c.Set("key3", "value", time.Duration(10) * time.Second)
+	if event := receive(t, ch); event.Type != EventEvict || event.Key != "key2" {
+		t.Errorf("event(%+v) is invalid.", event)
+	}
+	if event := receive(t, ch); event.Type != EventSet || event.Key != "key3" {
+		t.Errorf("event(%+v) is invalid.", event)
+	}
+}
+
+func TestOK_Watch_Drop(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{WatchBuffer: 2})
+	ch := c.Watch("")
+
+	for i := 0; i < 5; i++ {
+		c.Set("key", i, time.Duration(10) * time.Second)
+	}
+	if len(ch) != 2 {
+		t.Errorf("events(%d) is invalid.", len(ch))
+	}
+	c.Unwatch(ch)
+	for range ch {
+	}
+}
+
+func TestOK_Watch_Block(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := New(Option{WatchBuffer: 1, WatchOverflow: OverflowBlock})
+	ch := c.Watch("")
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 5; i++ {
+			c.Set("key", i, time.Duration(10) * time.Second)
+		}
+		close(done)
+	}()
+	for i := 0; i < 5; i++ {
+		if event := receive(t, ch); event.NewValue != i {
+			t.Errorf("event(%+v) is invalid.", event)
+		}
+	}
+	<-done
+
+	// blocked sender is released by Unwatch
+	go func() {
+		c.Set("key", 5, time.Duration(10) * time.Second)
+		c.Set("key", 6, time.Duration(10) * time.Second)
+		c.Unwatch(ch)
+	}()
+	time.Sleep(time.Duration(10) * time.Millisecond)
+	c.Close()
+	for range ch {
+	}
+}
+
+func TestOK_Watch_Typed(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	c := NewTyped[int, string](TypedOption[int, string]{})
+	ch := c.Watch("1")
+
+	c.Set(2, "value", time.Duration(10) * time.Second)
+	c.Set(12, "value", time.Duration(10) * time.Second)
+	c.Close()
+
+	events := []TypedEvent[int, string]{}
+	for event := range ch {
+		events = append(events, event)
+	}
+	if len(events) != 1 || events[0].Key != 12 {
+		t.Errorf("events(%+v) is invalid.", events)
+	}
+}
@@ -0,0 +1,246 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// WriteMode mode of writing to Writer
+type WriteMode int
+
+const (
+	// WriteThrough write to Writer synchronously on Set and Del (default)
+	WriteThrough WriteMode = iota
+	// WriteBehind queue writes, and write them to Writer in batches on WriteInterval
+	WriteBehind
+)
+
+const (
+	// DefaultWriteInterval interval of flushing in WriteBehind
+	DefaultWriteInterval time.Duration = 1 * time.Second
+	// DefaultWriteRetryInterval interval before first retry of failed write in WriteBehind
+	DefaultWriteRetryInterval time.Duration = 100 * time.Millisecond
+)
+
+// Writer writer writes items to backing store.
+type Writer[K comparable, V any] interface {
+	// Write write value of key.
+	// param key - key of item
+	// param value - value of item
+	// return arg1 - Error
+	Write(key K, value V) error
+
+	// Delete delete key.
+	// param key - key of item
+	// return arg1 - Error
+	Delete(key K) error
+}
+
+// BatchWriter is Writer which writes items at once.
+// If Writer implements BatchWriter, queued writes are flushed by WriteAll in WriteBehind.
+type BatchWriter[K comparable, V any] interface {
+	// WriteAll write values and delete keys at once.
+	// param values - values of keys to be written
+	// param deleted - keys to be deleted
+	// return arg1 - Error
+	WriteAll(values map[K]V, deleted []K) error
+}
+
+// write propagate Set or Del to Writer.
+// In WriteThrough, Writer is called synchronously. In WriteBehind, write is queued.
+// param key - key of item
+// param value - value of item
+// param deleted - If key is deleted, true.
+// return arg1 - Error of Writer
+func (c *cache[K, V]) write(key K, value V, deleted bool) error {
+	if c.option.Writer == nil {
+		return nil
+	}
+	if c.behind != nil {
+		c.behind.enqueue(key, value, deleted)
+		return nil
+	}
+	if deleted {
+		return c.option.Writer.Delete(key)
+	}
+	return c.option.Writer.Write(key, value)
+}
+
+// Flush write queued writes to Writer in WriteBehind.
+// return arg1 - Error of Writer
+func (c *cache[K, V]) Flush() error {
+	if c.behind == nil {
+		return nil
+	}
+	return c.behind.flush()
+}
+
+// Close stop optimizer and writer, flush queued writes, close channels of Watch, and sync append-only file.
+// Cache should not be set after Close.
+// return arg1 - Error of Writer and append-only file
+func (c *cache[K, V]) Close() error {
+	c.StopOptimizer()
+	c.unwatchAll()
+	var err error
+	if c.behind != nil {
+		err = c.behind.close()
+	}
+	return errors.Join(err, c.closeAOF())
+}
+
+// pendingWrite write queued in WriteBehind.
+type pendingWrite[V any] struct {
+	value V
+	deleted bool
+}
+
+// writeBehind queue of writes in WriteBehind.
+// Writes of same key are coalesced, and only the last write is flushed.
+type writeBehind[K comparable, V any] struct {
+	option *TypedOption[K, V]
+	mu sync.Mutex
+	pending map[K]pendingWrite[V]
+	flushMu sync.Mutex // serialize flushes, so that older write does not overwrite newer one
+	stop chan bool
+	done chan bool
+	closeOnce sync.Once
+	closeErr error
+}
+
+// newWriteBehind create queue and run flusher.
+// param opt - option
+// return arg1 - instance of writeBehind
+func newWriteBehind[K comparable, V any](opt *TypedOption[K, V]) *writeBehind[K, V] {
+	w := &writeBehind[K, V]{
+		option: opt,
+		pending: map[K]pendingWrite[V]{},
+		stop: make(chan bool),
+		done: make(chan bool),
+	}
+	go w.run()
+	return w
+}
+
+func (w *writeBehind[K, V]) enqueue(key K, value V, deleted bool) {
+	w.mu.Lock()
+	w.pending[key] = pendingWrite[V]{value: value, deleted: deleted}
+	n := len(w.pending)
+	w.mu.Unlock()
+	if 0 < w.option.WriteBatchSize && n >= w.option.WriteBatchSize {
+		// flush without waiting interval
+		go w.flush()
+	}
+}
+
+// run flush queued writes on interval until closed.
+func (w *writeBehind[K, V]) run() {
+	ticker := time.NewTicker(w.interval())
+	defer ticker.Stop()
+	defer close(w.done)
+	for {
+		select {
+		case <-ticker.C:
+			w.flush()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// interval get interval of flushing.
+func (w *writeBehind[K, V]) interval() time.Duration {
+	if w.option.WriteInterval <= 0 {
+		return DefaultWriteInterval
+	}
+	return w.option.WriteInterval
+}
+
+// close stop flusher, and flush remaining writes.
+func (w *writeBehind[K, V]) close() error {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+		w.closeErr = w.flush()
+	})
+	return w.closeErr
+}
+
+// flush write queued writes to Writer.
+// Failed writes are retried WriteRetries times, and then passed to OnWriteError and dropped.
+// return arg1 - Errors of Writer
+func (w *writeBehind[K, V]) flush() error {
+	w.flushMu.Lock()
+	defer w.flushMu.Unlock()
+
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = map[K]pendingWrite[V]{}
+	w.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	Debug("flush writes. count = %d", len(pending))
+
+	if batch, ok := w.option.Writer.(BatchWriter[K, V]); ok {
+		values := map[K]V{}
+		var deleted []K
+		for key, p := range pending {
+			if p.deleted {
+				deleted = append(deleted, key)
+			} else {
+				values[key] = p.value
+			}
+		}
+		err := w.retry(func() error { return batch.WriteAll(values, deleted) })
+		if err != nil {
+			for key := range pending {
+				w.fail(key, err)
+			}
+		}
+		return err
+	}
+
+	var errs []error
+	for key, p := range pending {
+		err := w.retry(func() error {
+			if p.deleted {
+				return w.option.Writer.Delete(key)
+			}
+			return w.option.Writer.Write(key, p.value)
+		})
+		if err != nil {
+			w.fail(key, err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// retry call fn until it succeeds, at most 1 + WriteRetries times.
+// Interval between attempts starts from WriteRetryInterval and is doubled up to the interval of flushing,
+// so that writes are not given up during short outage of backing store.
+func (w *writeBehind[K, V]) retry(fn func() error) (err error) {
+	wait := w.option.WriteRetryInterval
+	if wait <= 0 {
+		wait = DefaultWriteRetryInterval
+	}
+	for i := 0; i <= w.option.WriteRetries; i++ {
+		if i > 0 {
+			time.Sleep(wait)
+			wait = min(wait * 2, w.interval())
+		}
+		if err = fn(); err == nil {
+			return nil
+		}
+		Debug("write error. attempt = %d error = %v", i+1, err)
+	}
+	return err
+}
+
+func (w *writeBehind[K, V]) fail(key K, err error) {
+	Warn("write error key = %v error = %v", key, err)
+	if w.option.OnWriteError != nil {
+		w.option.OnWriteError(key, err)
+	}
+}
@@ -0,0 +1,353 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// mapWriter Writer which writes to map.
+type mapWriter struct {
+	mu sync.Mutex
+	values map[string]interface{}
+	writes int
+	deletes int
+	fails int // number of writes to be failed
+}
+
+func newMapWriter() *mapWriter {
+	return &mapWriter{values: map[string]interface{}{}}
+}
+
+func (w *mapWriter) Write(key string, value interface{}) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fails > 0 {
+		w.fails--
+		return errors.New("write error")
+	}
+	w.writes++
+	w.values[key] = value
+	return nil
+}
+
+func (w *mapWriter) Delete(key string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.fails > 0 {
+		w.fails--
+		return errors.New("delete error")
+	}
+	w.deletes++
+	delete(w.values, key)
+	return nil
+}
+
+func (w *mapWriter) get(key string) (interface{}, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	v, found := w.values[key]
+	return v, found
+}
+
+// batchMapWriter BatchWriter which writes to map.
+type batchMapWriter struct {
+	*mapWriter
+	batches int
+}
+
+func (w *batchMapWriter) WriteAll(values map[string]interface{}, deleted []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.batches++
+	for key, value := range values {
+		w.values[key] = value
+	}
+	for _, key := range deleted {
+		delete(w.values, key)
+	}
+	return nil
+}
+
+func TestOK_WriteThrough(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+	})
+
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	if v, found := w.get("key"); !found || v != "value" {
+		t.Errorf("written value(%v) is invalid.", v)
+	}
+
+	if err := c.Del("key"); err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if _, found := w.get("key"); found {
+		t.Errorf("key is not deleted from writer.")
+	}
+}
+
+func TestOK_WriteThrough_Load(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			Loader: LoaderFunc[string, interface{}](func(key string) (interface{}, time.Duration, error) {
+				return "loaded", time.Duration(10) * time.Second, nil
+			}),
+	})
+
+	// loaded value is not written back
+	c.Get("key")
+	if w.writes != 0 {
+		t.Errorf("writes(%d) is invalid.", w.writes)
+	}
+}
+
+func TestNG_WriteThrough_Error(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+	})
+
+	w.fails = 1
+	if err := c.Set("key", "value", time.Duration(10) * time.Second); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.Get("key"); found {
+		t.Errorf("item is set though writer failed.")
+	}
+
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	w.fails = 1
+	if err := c.Del("key"); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if _, found := c.Get("key"); !found {
+		t.Errorf("item is deleted though writer failed.")
+	}
+}
+
+func TestOK_WriteBehind_Coalesce(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+	})
+	defer c.Close()
+
+	c.Set("key1", "value1", time.Duration(10) * time.Second)
+	c.Set("key1", "value2", time.Duration(10) * time.Second)
+	c.Set("key2", "value", time.Duration(10) * time.Second)
+	c.Del("key2")
+
+	if _, found := w.get("key1"); found {
+		t.Errorf("written before flush.")
+	}
+	if err := c.Flush(); err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if v, _ := w.get("key1"); v != "value2" {
+		t.Errorf("written value(%v) is invalid.", v)
+	}
+	if w.writes != 1 || w.deletes != 1 {
+		t.Errorf("writes(%d) or deletes(%d) is invalid.", w.writes, w.deletes)
+	}
+}
+
+func TestOK_WriteBehind_Interval(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Duration(10) * time.Millisecond,
+	})
+	defer c.Close()
+
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	time.Sleep(time.Duration(100) * time.Millisecond)
+	if _, found := w.get("key"); !found {
+		t.Errorf("not flushed on interval.")
+	}
+}
+
+func TestOK_WriteBehind_Batch(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := &batchMapWriter{mapWriter: newMapWriter()}
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+	})
+
+	c.Set("key1", "value", time.Duration(10) * time.Second)
+	c.Set("key2", "value", time.Duration(10) * time.Second)
+	c.Del("key3")
+	c.Close()
+
+	if w.batches != 1 || len(w.values) != 2 {
+		t.Errorf("batches(%d) or values(%v) is invalid.", w.batches, w.values)
+	}
+}
+
+func TestOK_WriteBehind_Retry(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+			WriteRetries: 2,
+	})
+	defer c.Close()
+
+	w.fails = 2
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	if err := c.Flush(); err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if _, found := w.get("key"); !found {
+		t.Errorf("not written by retry.")
+	}
+}
+
+func TestOK_WriteBehind_RetryInterval(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+			WriteRetries: 2,
+			WriteRetryInterval: time.Duration(20) * time.Millisecond,
+	})
+	defer c.Close()
+
+	// retried after 20ms and 40ms
+	w.fails = 2
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	start := time.Now()
+	if err := c.Flush(); err != nil {
+		t.Errorf("error = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Duration(60) * time.Millisecond {
+		t.Errorf("elapsed(%v) is too short.", elapsed)
+	}
+	if _, found := w.get("key"); !found {
+		t.Errorf("not written by retry.")
+	}
+}
+
+func TestNG_WriteBehind_Error(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	var failed []string
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+			WriteRetries: 1,
+			OnWriteError: func(key string, err error) {
+				failed = append(failed, key)
+			},
+	})
+
+	w.fails = 2
+	c.Set("key", "value", time.Duration(10) * time.Second)
+	if err := c.Close(); err == nil {
+		t.Errorf("error is not returned.")
+	}
+	if len(failed) != 1 || failed[0] != "key" {
+		t.Errorf("failed(%v) is invalid.", failed)
+	}
+}
+
+func TestOK_WriteBehind_Close(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := newMapWriter()
+	c := New(Option{
+			Writer: w,
+			WriteMode: WriteBehind,
+			WriteInterval: time.Hour,
+	})
+
+	for i := 0; i < 100; i++ {
+		c.Set(string(rune('a' + i % 26)) + "key", i, time.Duration(10) * time.Second)
+	}
+	c.Close()
+	if len(w.values) != 26 {
+		t.Errorf("values(%d) is not flushed on close.", len(w.values))
+	}
+	// close again
+	if err := c.Close(); err != nil {
+		t.Errorf("error = %v", err)
+	}
+}
+
+// slowMapWriter mapWriter which returns slowly after writing.
+type slowMapWriter struct {
+	*mapWriter
+}
+
+func (w *slowMapWriter) Write(key string, value interface{}) error {
+	defer time.Sleep(time.Duration(value.(int) % 3) * time.Millisecond)
+	return w.mapWriter.Write(key, value)
+}
+
+func TestOK_WriteThrough_Concurrent(t *testing.T) {
+	// enable logger
+	EnableLogger(true)
+
+	w := &slowMapWriter{newMapWriter()}
+	c := New(Option{Writer: w})
+	for i := 0; i < 100; i++ {
+		// concurrent Set and Del of same key
+		var wg sync.WaitGroup
+		for g := 0; g < 3; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				if g == 2 && i % 2 == 0 {
+					c.Del("key")
+					return
+				}
+				c.Set("key", i * 10 + g, time.Duration(10) * time.Second)
+			}(g)
+		}
+		wg.Wait()
+
+		// cache and backing store end with same value
+		cached, found := c.Get("key")
+		written, writtenFound := w.get("key")
+		if found != writtenFound || (found && *cached != written) {
+			t.Fatalf("cached(%v) is different from written(%v).", cached, written)
+		}
+	}
+}